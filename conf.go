@@ -0,0 +1,318 @@
+package logx
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	tagName       = "json"
+	defaultOption = "default"
+	optionsOption = "options"
+	optionalOpt   = "optional"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// fieldTag is the parsed form of tags like `json:",default=console,options=[console,file]"`.
+type fieldTag struct {
+	name         string
+	defaultValue string
+	hasDefault   bool
+	options      []string
+	optional     bool
+}
+
+// LoadFromFile 从 json 或 yaml 文件中解析日志配置并加载
+func LoadFromFile(file string) error {
+	c, err := ParseFile(file)
+	if err != nil {
+		return err
+	}
+
+	return Load(c)
+}
+
+// LoadFromEnv 从以 prefix 开头的环境变量中解析日志配置并加载
+func LoadFromEnv(prefix string) error {
+	c, err := ParseEnv(prefix)
+	if err != nil {
+		return err
+	}
+
+	return Load(c)
+}
+
+// ParseFile 从 json 或 yaml 文件中解析日志配置，文件类型由扩展名决定。
+// 未设置的字段使用 tag 中声明的默认值，不在 options 中的值会返回错误
+func ParseFile(file string) (LogConf, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return LogConf{}, err
+	}
+
+	values := make(map[string]interface{})
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.UseNumber()
+		if err = decoder.Decode(&values); err != nil {
+			return LogConf{}, fmt.Errorf("logx: parse %s: %w", file, err)
+		}
+	case ".yaml", ".yml":
+		if err = yaml.Unmarshal(content, &values); err != nil {
+			return LogConf{}, fmt.Errorf("logx: parse %s: %w", file, err)
+		}
+	default:
+		return LogConf{}, fmt.Errorf("logx: unsupported config file type: %s", file)
+	}
+
+	return fillConf(values)
+}
+
+// ParseEnv 从环境变量中解析日志配置，变量名为 prefix_字段名，字段名按驼峰拆分为大写下划线形式，
+// 例如 prefix 为 LOGX 时，PlainEncodingSep 对应 LOGX_PLAIN_ENCODING_SEP。
+// 未设置的字段使用 tag 中声明的默认值，不在 options 中的值会返回错误
+func ParseEnv(prefix string) (LogConf, error) {
+	values := make(map[string]interface{})
+	rt := reflect.TypeOf(LogConf{})
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		name := envName(prefix, field.Name)
+		if val, ok := os.LookupEnv(name); ok {
+			values[field.Name] = val
+		}
+	}
+
+	return fillConf(values)
+}
+
+// fillConf fills a LogConf with values, the keys are matched with the field names case-insensitively.
+func fillConf(values map[string]interface{}) (LogConf, error) {
+	lowered := make(map[string]interface{}, len(values))
+	for key, val := range values {
+		lowered[strings.ToLower(key)] = val
+	}
+
+	var c LogConf
+	rv := reflect.ValueOf(&c).Elem()
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		tag := parseFieldTag(field)
+
+		val, ok := lowered[strings.ToLower(tag.name)]
+		switch {
+		case ok:
+			if err := setFieldValue(rv.Field(i), val); err != nil {
+				return LogConf{}, fmt.Errorf("logx: field %s: %w", field.Name, err)
+			}
+		case tag.hasDefault:
+			if err := setFieldValue(rv.Field(i), tag.defaultValue); err != nil {
+				return LogConf{}, fmt.Errorf("logx: field %s: bad default value: %w", field.Name, err)
+			}
+		case !tag.optional:
+			return LogConf{}, fmt.Errorf("logx: field %s is not set", field.Name)
+		}
+	}
+
+	if err := c.Validate(); err != nil {
+		return LogConf{}, err
+	}
+
+	return c, nil
+}
+
+// checkOptions checks if the value of v is one of the options declared in tag.
+func checkOptions(tag fieldTag, v reflect.Value) error {
+	if len(tag.options) == 0 {
+		return nil
+	}
+
+	val := fmt.Sprint(v.Interface())
+	for _, option := range tag.options {
+		if val == option {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("value %q is not in options [%s]", val, strings.Join(tag.options, ","))
+}
+
+func parseFieldTag(field reflect.StructField) fieldTag {
+	tag := fieldTag{
+		name: field.Name,
+	}
+
+	segments := splitTag(field.Tag.Get(tagName))
+	if len(segments) == 0 {
+		return tag
+	}
+	if len(segments[0]) > 0 {
+		tag.name = segments[0]
+	}
+
+	for _, segment := range segments[1:] {
+		key, val := segment, ""
+		if idx := strings.IndexByte(segment, '='); idx >= 0 {
+			key, val = segment[:idx], segment[idx+1:]
+		}
+
+		switch key {
+		case defaultOption:
+			tag.hasDefault = true
+			tag.defaultValue = unescape(val)
+		case optionsOption:
+			val = strings.TrimSuffix(strings.TrimPrefix(val, "["), "]")
+			if len(val) > 0 {
+				tag.options = strings.Split(val, ",")
+			}
+		case optionalOpt:
+			tag.optional = true
+		}
+	}
+
+	return tag
+}
+
+// splitTag splits the tag by commas, except the ones inside brackets.
+func splitTag(tag string) []string {
+	var (
+		segments []string
+		depth    int
+		start    int
+	)
+	for i, r := range tag {
+		switch r {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case ',':
+			if depth == 0 {
+				segments = append(segments, tag[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	return append(segments, tag[start:])
+}
+
+// unescape converts escapes like \t in the tag to the characters they represent.
+func unescape(val string) string {
+	if unquoted, err := strconv.Unquote(`"` + val + `"`); err == nil {
+		return unquoted
+	}
+
+	return val
+}
+
+func setFieldValue(v reflect.Value, val interface{}) error {
+	if v.Type() == durationType {
+		return setDurationValue(v, val)
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		switch s := val.(type) {
+		case string:
+			v.SetString(s)
+		case json.Number, int, int64, float64, bool:
+			v.SetString(fmt.Sprint(s))
+		default:
+			return fmt.Errorf("cannot use %v as string", val)
+		}
+	case reflect.Bool:
+		switch b := val.(type) {
+		case bool:
+			v.SetBool(b)
+		case string:
+			parsed, err := strconv.ParseBool(b)
+			if err != nil {
+				return err
+			}
+			v.SetBool(parsed)
+		default:
+			return fmt.Errorf("cannot use %v as bool", val)
+		}
+	case reflect.Int, reflect.Int64:
+		n, err := toInt64(val)
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	default:
+		return fmt.Errorf("unsupported field type %s", v.Type())
+	}
+
+	return nil
+}
+
+// setDurationValue accepts strings like "1s", or integers as nanoseconds.
+func setDurationValue(v reflect.Value, val interface{}) error {
+	if s, ok := val.(string); ok {
+		if d, err := time.ParseDuration(s); err == nil {
+			v.SetInt(int64(d))
+			return nil
+		}
+	}
+
+	n, err := toInt64(val)
+	if err != nil {
+		return fmt.Errorf("cannot use %v as duration", val)
+	}
+	v.SetInt(n)
+
+	return nil
+}
+
+func toInt64(val interface{}) (int64, error) {
+	switch n := val.(type) {
+	case int:
+		return int64(n), nil
+	case int64:
+		return n, nil
+	case uint64:
+		return int64(n), nil
+	case float64:
+		if n != float64(int64(n)) {
+			return 0, fmt.Errorf("cannot use %v as integer", val)
+		}
+		return int64(n), nil
+	case json.Number:
+		return n.Int64()
+	case string:
+		return strconv.ParseInt(n, 10, 64)
+	default:
+		return 0, fmt.Errorf("cannot use %v as integer", val)
+	}
+}
+
+// envName returns the environment variable name of field, like LOGX_PLAIN_ENCODING_SEP.
+func envName(prefix, field string) string {
+	var buf strings.Builder
+	if len(prefix) > 0 {
+		buf.WriteString(prefix)
+		buf.WriteByte('_')
+	}
+
+	for i, r := range field {
+		if i > 0 && unicode.IsUpper(r) {
+			buf.WriteByte('_')
+		}
+		buf.WriteRune(unicode.ToUpper(r))
+	}
+
+	return buf.String()
+}
@@ -0,0 +1,130 @@
+package logx
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFileJson(t *testing.T) {
+	file := path.Join(t.TempDir(), "logx.json")
+	assert.Nil(t, os.WriteFile(file, []byte(`{
+	"Mode": "file",
+	"encoding": "plain",
+	"MaxSize": 10,
+	"Compress": true,
+	"FlushInterval": "500ms"
+}`), defaultFileMode))
+
+	c, err := ParseFile(file)
+	assert.Nil(t, err)
+	assert.Equal(t, LogConf{
+		Mode:             fileMode,
+		Encoding:         plainEncoding,
+		PlainEncodingSep: "\t",
+		Path:             "logs",
+		Level:            levelInfo,
+		Rotation:         noneRotation,
+		MaxSize:          10,
+		Compress:         true,
+		QueueSize:        defaultQueueSize,
+		Overflow:         blockOverflow,
+		BufferSize:       defaultBufferSize,
+		FlushInterval:    500 * time.Millisecond,
+		Facility:         "user",
+		MaxSpoolSize:     defaultMaxSpoolSize,
+	}, c)
+}
+
+func TestParseFileYaml(t *testing.T) {
+	file := path.Join(t.TempDir(), "logx.yaml")
+	assert.Nil(t, os.WriteFile(file, []byte(`
+Mode: file
+Path: /var/log/app
+Level: warn
+Rotation: daily
+KeepDays: 7
+`), defaultFileMode))
+
+	c, err := ParseFile(file)
+	assert.Nil(t, err)
+	assert.Equal(t, fileMode, c.Mode)
+	assert.Equal(t, "json", c.Encoding)
+	assert.Equal(t, "/var/log/app", c.Path)
+	assert.Equal(t, levelWarn, c.Level)
+	assert.Equal(t, dailyRotation, c.Rotation)
+	assert.Equal(t, 7, c.KeepDays)
+	assert.Equal(t, time.Second, c.FlushInterval)
+}
+
+func TestParseFileErrors(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		err     string
+	}{
+		{
+			name:    "options",
+			file:    "logx.json",
+			content: `{"Mode": "files"}`,
+			err:     `logx: invalid LogConf: Mode: value "files" is not in options [console,file,syslog]`,
+		},
+		{
+			name:    "type",
+			file:    "logx.yml",
+			content: `MaxSize: ten`,
+			err:     `logx: field MaxSize: strconv.ParseInt: parsing "ten": invalid syntax`,
+		},
+		{
+			name:    "extension",
+			file:    "logx.toml",
+			content: `Mode = "file"`,
+			err:     "logx: unsupported config file type: " + path.Join(dir, "logx.toml"),
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			file := path.Join(dir, test.file)
+			assert.Nil(t, os.WriteFile(file, []byte(test.content), defaultFileMode))
+			_, err := ParseFile(file)
+			assert.EqualError(t, err, test.err)
+		})
+	}
+
+	_, err := ParseFile(path.Join(dir, "not-exist.json"))
+	assert.NotNil(t, err)
+}
+
+func TestParseEnv(t *testing.T) {
+	t.Setenv("LOGX_MODE", fileMode)
+	t.Setenv("LOGX_PLAIN_ENCODING_SEP", "|")
+	t.Setenv("LOGX_WITH_COLOR", "true")
+	t.Setenv("LOGX_QUEUE_SIZE", "1000")
+	t.Setenv("LOGX_FLUSH_INTERVAL", "2s")
+
+	c, err := ParseEnv("LOGX")
+	assert.Nil(t, err)
+	assert.Equal(t, fileMode, c.Mode)
+	assert.Equal(t, "|", c.PlainEncodingSep)
+	assert.True(t, c.WithColor)
+	assert.Equal(t, 1000, c.QueueSize)
+	assert.Equal(t, 2*time.Second, c.FlushInterval)
+	assert.Equal(t, "logs", c.Path)
+
+	t.Setenv("LOGX_OVERFLOW", "drop")
+	_, err = ParseEnv("LOGX")
+	assert.EqualError(t, err, `logx: invalid LogConf: Overflow: value "drop" is not in options [block,drop_newest,drop_oldest]`)
+}
+
+func TestEnvName(t *testing.T) {
+	assert.Equal(t, "LOGX_MODE", envName("LOGX", "Mode"))
+	assert.Equal(t, "LOGX_PLAIN_ENCODING_SEP", envName("LOGX", "PlainEncodingSep"))
+	assert.Equal(t, "MAX_SIZE", envName("", "MaxSize"))
+}
@@ -0,0 +1,97 @@
+package logx
+
+import (
+	"context"
+	"sync"
+)
+
+const (
+	spanKey  = "span_id"
+	traceKey = "trace_id"
+)
+
+// A ContextExtractor extracts log fields from ctx, it's called on every WithContext.
+type ContextExtractor func(ctx context.Context) []LogField
+
+type fieldsContextKey struct{}
+
+var (
+	extractorsLock sync.RWMutex
+	extractors     []ContextExtractor
+)
+
+// RegisterContextExtractor 注册 context 字段提取器，WithContext 时按注册顺序提取字段
+func RegisterContextExtractor(extractor ContextExtractor) {
+	extractorsLock.Lock()
+	defer extractorsLock.Unlock()
+	extractors = append(extractors, extractor)
+}
+
+// ContextWithFields 返回携带日志字段的 context，WithContext 时这些字段会被写入日志
+func ContextWithFields(ctx context.Context, fields ...LogField) context.Context {
+	if val, ok := ctx.Value(fieldsContextKey{}).([]LogField); ok {
+		merged := make([]LogField, 0, len(val)+len(fields))
+		merged = append(merged, val...)
+		fields = append(merged, fields...)
+	}
+
+	return context.WithValue(ctx, fieldsContextKey{}, fields)
+}
+
+// ValueExtractor 返回将 ctx.Value(ctxKey) 以 key 写入日志的提取器，值不存在时不写入
+func ValueExtractor(key string, ctxKey interface{}) ContextExtractor {
+	return func(ctx context.Context) []LogField {
+		val := ctx.Value(ctxKey)
+		if val == nil {
+			return nil
+		}
+
+		return []LogField{Field(key, val)}
+	}
+}
+
+// TraceExtractor 返回将 trace_id 和 span_id 写入日志的提取器，
+// fn 从 ctx 中取出 trace id 和 span id，例如通过 OpenTelemetry 的 trace.SpanContextFromContext
+func TraceExtractor(fn func(ctx context.Context) (traceID, spanID string)) ContextExtractor {
+	return func(ctx context.Context) []LogField {
+		traceID, spanID := fn(ctx)
+		var fields []LogField
+		if len(traceID) > 0 {
+			fields = append(fields, String(traceKey, traceID))
+		}
+		if len(spanID) > 0 {
+			fields = append(fields, String(spanKey, spanID))
+		}
+
+		return fields
+	}
+}
+
+// WithContext 返回带有 ctx 中字段的 Logger，日志写入全局 writer
+func WithContext(ctx context.Context) Logger {
+	return With(contextFields(ctx)...)
+}
+
+// WithContext 返回带有 ctx 中字段的子 Logger，子 Logger 与 l 共用同一个 writer
+func (l *logger) WithContext(ctx context.Context) Logger {
+	return l.With(contextFields(ctx)...)
+}
+
+func contextFields(ctx context.Context) []LogField {
+	if ctx == nil {
+		return nil
+	}
+
+	var fields []LogField
+	if val, ok := ctx.Value(fieldsContextKey{}).([]LogField); ok {
+		fields = append(fields, val...)
+	}
+
+	extractorsLock.RLock()
+	defer extractorsLock.RUnlock()
+	for _, extract := range extractors {
+		fields = append(fields, extract(ctx)...)
+	}
+
+	return fields
+}
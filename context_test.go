@@ -0,0 +1,90 @@
+package logx
+
+import (
+	"context"
+	"encoding/json"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type requestIDKey struct{}
+
+func TestWithContext(t *testing.T) {
+	w := new(mockWriter)
+	old := writer.Swap(w)
+	defer writer.Store(old)
+	oldLevel := atomic.LoadUint32(&logLevel)
+	SetLevel(InfoLevel)
+	defer SetLevel(oldLevel)
+	defer resetExtractors()
+
+	RegisterContextExtractor(ValueExtractor("request_id", requestIDKey{}))
+	RegisterContextExtractor(TraceExtractor(func(ctx context.Context) (string, string) {
+		return "trace-1", "span-1"
+	}))
+
+	ctx := context.WithValue(context.Background(), requestIDKey{}, "r1")
+	ctx = ContextWithFields(ctx, String("tenant", "t1"))
+	WithContext(ctx).Infow("hello there", String("foo", "bar"))
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(w.String()), &entry); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "r1", entry["request_id"])
+	assert.Equal(t, "t1", entry["tenant"])
+	assert.Equal(t, "trace-1", entry[traceKey])
+	assert.Equal(t, "span-1", entry[spanKey])
+	assert.Equal(t, "bar", entry["foo"])
+}
+
+func TestWithContextPlainText(t *testing.T) {
+	w := new(mockWriter)
+	old := writer.Swap(w)
+	defer writer.Store(old)
+	oldLevel := atomic.LoadUint32(&logLevel)
+	SetLevel(InfoLevel)
+	defer SetLevel(oldLevel)
+	defer resetExtractors()
+
+	oldEncoder := loadEncoderConf()
+	storeEncoderConf(newEncoderConf(LogConf{Encoding: plainEncoding}))
+	defer storeEncoderConf(oldEncoder)
+
+	RegisterContextExtractor(TraceExtractor(func(ctx context.Context) (string, string) {
+		return "trace-1", ""
+	}))
+
+	l := &logger{lw: w}
+	l.WithContext(context.Background()).Info("hello there")
+	assert.True(t, w.Contains("trace_id=trace-1"))
+	assert.False(t, w.Contains(spanKey))
+}
+
+func TestWithContextMissingValue(t *testing.T) {
+	w := new(mockWriter)
+	old := writer.Swap(w)
+	defer writer.Store(old)
+	oldLevel := atomic.LoadUint32(&logLevel)
+	SetLevel(InfoLevel)
+	defer SetLevel(oldLevel)
+	defer resetExtractors()
+
+	RegisterContextExtractor(ValueExtractor("request_id", requestIDKey{}))
+	WithContext(context.Background()).Info("hello there")
+	assert.False(t, w.Contains("request_id"))
+}
+
+func TestContextWithFieldsNested(t *testing.T) {
+	ctx := ContextWithFields(context.Background(), String("a", "1"))
+	ctx = ContextWithFields(ctx, String("b", "2"))
+	assert.Equal(t, []LogField{String("a", "1"), String("b", "2")}, contextFields(ctx))
+}
+
+func resetExtractors() {
+	extractorsLock.Lock()
+	defer extractorsLock.Unlock()
+	extractors = nil
+}
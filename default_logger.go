@@ -1,78 +1,170 @@
 package logx
 
 import (
+	"bufio"
 	"errors"
+	"fmt"
 	"github.com/git-zjx/logx/fs"
 	"log"
 	"os"
 	"path"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
-var ErrLogFileClosed = errors.New("error: log file closed")
+var (
+	ErrLogFileClosed   = errors.New("error: log file closed")
+	ErrLogFlushTimeout = errors.New("error: log flush timeout")
+)
 
 type (
 	// A DefaultLogger is a Logger.
 	DefaultLogger struct {
+		*asyncQueue
 		filename string
 		fp       *os.File
-		channel  chan []byte
-		done     chan struct{}
-		// can't use threading.RoutineGroup because of cycle import
-		waitGroup sync.WaitGroup
-		closeOnce sync.Once
+		// buf batches the writes to fp, nil if buffering is disabled
+		buf           *bufio.Writer
+		bufferSize    int
+		flushInterval time.Duration
+		currentSize   int64
+		rule          rotateRule
+		compress      bool
+		// encoder encodes the notice written after the file is recreated
+		encoder Encoder
+		// reportedDropped is only accessed by the worker
+		reportedDropped uint64
+		flushes         chan chan error
+		reopens         chan chan error
+		urgent          chan struct{}
+		// closeErr is set by the worker before it closes stopped
+		closeErr error
+		// postGroup tracks the background compression and cleanup after rotation
+		postGroup sync.WaitGroup
+		postLock  sync.Mutex
 	}
 )
 
+// fileCheckInterval is how often the worker checks if the file is removed or moved, replaceable in tests.
+var fileCheckInterval = 10 * time.Second
+
 const (
-	bufferSize      = 100
-	defaultDirMode  = 0o755
-	defaultFileMode = 0o600
+	defaultQueueSize     = 100
+	defaultBufferSize    = 4096
+	defaultFlushInterval = time.Second
+	defaultDirMode       = 0o755
+	defaultFileMode      = 0o600
+	dropReportInterval   = time.Minute
+
+	blockOverflow      = "block"
+	dropNewestOverflow = "drop_newest"
+	dropOldestOverflow = "drop_oldest"
 )
 
-// NewLogger returns a DefaultLogger with given filename and rule, etc.
+// NewLogger returns a DefaultLogger with given filename, the file is never rotated.
 func NewLogger(filename string) (*DefaultLogger, error) {
+	return newLogger(filename, LogConf{})
+}
+
+// newLogger returns a DefaultLogger with given filename, rotated by the rules in c.
+func newLogger(filename string, c LogConf) (*DefaultLogger, error) {
+	bufferSize := c.BufferSize
+	if bufferSize == 0 {
+		bufferSize = defaultBufferSize
+	}
+	flushInterval := c.FlushInterval
+	if flushInterval <= 0 {
+		flushInterval = defaultFlushInterval
+	}
+
 	l := &DefaultLogger{
-		filename: filename,
-		channel:  make(chan []byte, bufferSize),
-		done:     make(chan struct{}),
+		asyncQueue:    newAsyncQueue(c.QueueSize, c.Overflow),
+		filename:      filename,
+		bufferSize:    bufferSize,
+		flushInterval: flushInterval,
+		rule:          newRotateRule(filename, c),
+		compress:      c.Compress,
+		encoder:       newEncoderConf(c).encoder,
+		flushes:       make(chan chan error),
+		reopens:       make(chan chan error),
+		urgent:        make(chan struct{}, 1),
 	}
 	if err := l.init(); err != nil {
 		return nil, err
 	}
 
 	l.startWorker()
+	registerFileLogger(l)
 	return l, nil
 }
 
-// Close closes l.
+// Close closes l after all the accepted entries are written and synced to the file.
 func (l *DefaultLogger) Close() error {
-	var err error
+	return l.CloseTimeout(0)
+}
 
-	l.closeOnce.Do(func() {
-		close(l.done)
-		l.waitGroup.Wait()
+// CloseTimeout closes l, waiting at most timeout for the accepted entries to be written.
+// A non-positive timeout means waiting until all of them are written.
+func (l *DefaultLogger) CloseTimeout(timeout time.Duration) error {
+	unregisterFileLogger(l)
+	l.shutdown()
 
-		if err = l.fp.Sync(); err != nil {
-			return
-		}
+	if err := waitTimeout(l.stopped, timeout); err != nil {
+		return err
+	}
 
-		err = l.fp.Close()
-	})
+	return l.closeErr
+}
 
-	return err
+// Flush blocks until all the entries accepted before the call are written and synced to the file.
+func (l *DefaultLogger) Flush() error {
+	return l.FlushTimeout(0)
 }
 
-func (l *DefaultLogger) Write(data []byte) (int, error) {
+// FlushTimeout is like Flush, but waits at most timeout. A non-positive timeout means no limit.
+func (l *DefaultLogger) FlushTimeout(timeout time.Duration) error {
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	result := make(chan error, 1)
 	select {
-	case l.channel <- data:
-		return len(data), nil
+	case l.flushes <- result:
 	case <-l.done:
-		log.Println(string(data))
-		return 0, ErrLogFileClosed
+		return ErrLogFileClosed
+	case <-expired:
+		return ErrLogFlushTimeout
+	}
+
+	select {
+	case err := <-result:
+		return err
+	case <-expired:
+		return ErrLogFlushTimeout
 	}
 }
 
+// Reopen closes the file and opens it again by the filename, so that the entries written after
+// the file is renamed, like by logrotate, go to a new file. The queued entries are kept.
+func (l *DefaultLogger) Reopen() error {
+	result := make(chan error, 1)
+	select {
+	case l.reopens <- result:
+	case <-l.done:
+		return ErrLogFileClosed
+	}
+
+	return <-result
+}
+
+func (l *DefaultLogger) Write(data []byte) (int, error) {
+	return l.push(data, ErrLogFileClosed)
+}
+
 func (l *DefaultLogger) init() error {
 
 	if _, err := os.Stat(l.filename); err != nil {
@@ -92,28 +184,290 @@ func (l *DefaultLogger) init() error {
 
 	fs.CloseOnExec(l.fp)
 
+	if l.bufferSize > 0 {
+		if l.buf == nil {
+			l.buf = bufio.NewWriterSize(l.fp, l.bufferSize)
+		} else {
+			l.buf.Reset(l.fp)
+		}
+	}
+
+	info, err := l.fp.Stat()
+	if err != nil {
+		return err
+	}
+	l.currentSize = info.Size()
+
 	return nil
 }
 
-func (l *DefaultLogger) startWorker() {
-	l.waitGroup.Add(1)
+// reopen closes the current file and opens the file named filename, which is created if renamed or removed.
+func (l *DefaultLogger) reopen() error {
+	if err := l.closeFile(); err != nil {
+		log.Println(err.Error())
+	}
+
+	return l.init()
+}
+
+// checkFile recreates the file if it's removed or moved, like deleted by mistake, otherwise the entries
+// would be written to the unlinked file. A notice is written to the new file.
+func (l *DefaultLogger) checkFile() {
+	if l.fp != nil && !l.fileMoved() {
+		return
+	}
+
+	if err := l.reopen(); err != nil {
+		log.Printf("recreate log file %s failed: %s", l.filename, err.Error())
+		return
+	}
+
+	l.writeNotice(fmt.Sprintf("log file %s was removed or moved, recreated", l.filename))
+}
+
+// fileMoved checks if filename no longer refers to the open file.
+func (l *DefaultLogger) fileMoved() bool {
+	info, err := os.Stat(l.filename)
+	if err != nil {
+		return os.IsNotExist(err)
+	}
+
+	current, err := l.fp.Stat()
+	if err != nil {
+		return false
+	}
+
+	return !os.SameFile(info, current)
+}
+
+// writeNotice writes msg as a warn entry, encoded like the other entries.
+func (l *DefaultLogger) writeNotice(msg string) {
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	if err := l.encoder.Encode(buf, Entry{
+		Time:    time.Now(),
+		Level:   levelWarn,
+		Content: msg,
+	}); err != nil {
+		log.Println(err.Error())
+		return
+	}
+
+	buf.WriteByte('\n')
+	l.write(buf.Bytes())
+}
+
+// rotate closes the current file, renames it to the backup name and opens a fresh one.
+func (l *DefaultLogger) rotate() error {
+	if err := l.closeFile(); err != nil {
+		return err
+	}
+
+	backup := l.rule.BackupFileName()
+	if err := os.Rename(l.filename, backup); err != nil {
+		if !os.IsNotExist(err) {
+			return err
+		}
+		backup = ""
+	}
+
+	l.rule.MarkRotated()
+
+	l.postGroup.Add(1)
+	go func() {
+		defer l.postGroup.Done()
+		l.postRotate(backup)
+	}()
+
+	return l.init()
+}
 
+// postRotate compresses the backup if required and removes the outdated backups,
+// it's serialized by postLock so that cleanups never race with compressions.
+func (l *DefaultLogger) postRotate(backup string) {
+	l.postLock.Lock()
+	defer l.postLock.Unlock()
+
+	if l.compress && len(backup) > 0 {
+		if err := compressFile(backup); err != nil {
+			log.Printf("compress log file %s failed: %s", backup, err.Error())
+		}
+	}
+
+	for _, file := range l.rule.OutdatedFiles() {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			log.Printf("remove outdated log file %s failed: %s", file, err.Error())
+		}
+	}
+}
+
+func (l *DefaultLogger) startWorker() {
 	go func() {
-		defer l.waitGroup.Done()
+		defer close(l.stopped)
+
+		ticker := time.NewTicker(dropReportInterval)
+		defer ticker.Stop()
+		flushTicker := time.NewTicker(l.flushInterval)
+		defer flushTicker.Stop()
+		checkTicker := time.NewTicker(fileCheckInterval)
+		defer checkTicker.Stop()
 
 		for {
 			select {
 			case event := <-l.channel:
 				l.write(event)
+			case result := <-l.flushes:
+				result <- l.flush()
+			case result := <-l.reopens:
+				result <- l.reopen()
+			case <-l.urgent:
+				l.drain()
+				l.flushBuffer()
+			case <-flushTicker.C:
+				l.flushBuffer()
+			case <-checkTicker.C:
+				l.checkFile()
+			case <-ticker.C:
+				l.reportDropped()
 			case <-l.done:
+				l.reportDropped()
+				l.closeErr = l.stop()
 				return
 			}
 		}
 	}()
 }
 
+// reportDropped prints the number of entries dropped since the last report, if any.
+func (l *DefaultLogger) reportDropped() {
+	dropped := atomic.LoadUint64(&l.dropped)
+	if dropped == l.reportedDropped {
+		return
+	}
+
+	log.Printf("%d log entries dropped on %s since last report, %d in total",
+		dropped-l.reportedDropped, l.filename, dropped)
+	l.reportedDropped = dropped
+}
+
+// drain writes the entries queued at the moment of the call.
+func (l *DefaultLogger) drain() {
+	for n := len(l.channel); n > 0; n-- {
+		l.write(<-l.channel)
+	}
+}
+
+func (l *DefaultLogger) flush() error {
+	l.drain()
+
+	if l.fp == nil {
+		return nil
+	}
+
+	if l.buf != nil {
+		if err := l.buf.Flush(); err != nil {
+			return err
+		}
+	}
+
+	return l.fp.Sync()
+}
+
+// flushBuffer writes the buffered entries to the file without syncing.
+func (l *DefaultLogger) flushBuffer() {
+	if l.fp == nil || l.buf == nil || l.buf.Buffered() == 0 {
+		return
+	}
+
+	if err := l.buf.Flush(); err != nil {
+		log.Println(err.Error())
+	}
+}
+
+// flushAsync asks the worker to write out the entries queued so far without waiting,
+// it's used to make error entries durable promptly.
+func (l *DefaultLogger) flushAsync() {
+	select {
+	case l.urgent <- struct{}{}:
+	default:
+	}
+}
+
+// closeFile flushes the buffer and closes the current file.
+func (l *DefaultLogger) closeFile() error {
+	if l.fp == nil {
+		return nil
+	}
+
+	var err error
+	if l.buf != nil {
+		err = l.buf.Flush()
+	}
+	if closeErr := l.fp.Close(); err == nil {
+		err = closeErr
+	}
+	l.fp = nil
+
+	return err
+}
+
+// stop is called by the worker after done is closed, no more entries can be queued by then.
+func (l *DefaultLogger) stop() error {
+	err := l.flush()
+	l.postGroup.Wait()
+
+	if closeErr := l.closeFile(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
 func (l *DefaultLogger) write(v []byte) {
-	if l.fp != nil {
-		_, _ = l.fp.Write(v)
+	if l.rule != nil && l.rule.ShallRotate(l.currentSize+int64(len(v))) {
+		if l.currentSize == 0 {
+			// nothing to back up, just start the new period
+			l.rule.MarkRotated()
+		} else if err := l.rotate(); err != nil {
+			log.Println(err.Error())
+		}
+	}
+
+	if l.fp == nil {
+		return
+	}
+
+	// every entry goes to the file in a single write, so that it's never interleaved with the
+	// writes of others appending to the same file, like the old logger on Reload
+	if l.buf != nil && len(v) > l.buf.Available() {
+		if err := l.buf.Flush(); err != nil {
+			log.Println(err.Error())
+		}
+	}
+
+	var n int
+	if l.buf != nil && len(v) <= l.buf.Available() {
+		n, _ = l.buf.Write(v)
+	} else {
+		n, _ = l.fp.Write(v)
+	}
+	l.currentSize += int64(n)
+}
+
+func waitTimeout(stopped <-chan struct{}, timeout time.Duration) error {
+	if timeout <= 0 {
+		<-stopped
+		return nil
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-timer.C:
+		return ErrLogFlushTimeout
 	}
 }
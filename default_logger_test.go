@@ -0,0 +1,405 @@
+package logx
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultLoggerRotateBySize(t *testing.T) {
+	filename := path.Join(t.TempDir(), "logx.log")
+	l, err := newLogger(filename, LogConf{MaxSize: 1})
+	assert.Nil(t, err)
+
+	line := make([]byte, megaBytes/2)
+	for i := 0; i < 5; i++ {
+		_, err = l.Write(line)
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, l.Close())
+
+	backups, err := filepath.Glob(filename + backupDelimiter + "*")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(backups))
+	for _, backup := range backups {
+		info, err := os.Stat(backup)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(megaBytes), info.Size())
+	}
+
+	info, err := os.Stat(filename)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(megaBytes/2), info.Size())
+}
+
+func TestDefaultLoggerRotateDaily(t *testing.T) {
+	now := time.Date(2026, 10, 15, 23, 59, 0, 0, time.Local)
+	currentTime = func() time.Time {
+		return now
+	}
+	defer func() {
+		currentTime = time.Now
+	}()
+
+	filename := path.Join(t.TempDir(), "logx.log")
+	l, err := newLogger(filename, LogConf{Rotation: dailyRotation})
+	assert.Nil(t, err)
+	_, err = l.Write([]byte("yesterday\n"))
+	assert.Nil(t, err)
+	// the worker reads now, so it's only changed after the queued entry is written
+	assert.Nil(t, l.Flush())
+	now = now.Add(time.Minute)
+	_, err = l.Write([]byte("today\n"))
+	assert.Nil(t, err)
+	assert.Nil(t, l.Close())
+
+	content, err := os.ReadFile(filename + ".2026-10-15")
+	assert.Nil(t, err)
+	assert.Equal(t, "yesterday\n", string(content))
+	content, err = os.ReadFile(filename)
+	assert.Nil(t, err)
+	assert.Equal(t, "today\n", string(content))
+}
+
+func TestPeriodRotateRuleBackupSequence(t *testing.T) {
+	filename := path.Join(t.TempDir(), "logx.log")
+	rule := &periodRotateRule{
+		filename:    filename,
+		layout:      hourlyLayout,
+		rotatedTime: "2026-10-15T10",
+	}
+
+	backup := rule.BackupFileName()
+	assert.Equal(t, filename+".2026-10-15T10", backup)
+	assert.Nil(t, os.WriteFile(backup, nil, defaultFileMode))
+	assert.Equal(t, filename+".2026-10-15T10.1", rule.BackupFileName())
+}
+
+func TestNewRotateRuleDisabled(t *testing.T) {
+	assert.Nil(t, newRotateRule("logx.log", LogConf{}))
+	assert.Nil(t, newRotateRule("logx.log", LogConf{Rotation: noneRotation}))
+	assert.NotNil(t, newRotateRule("logx.log", LogConf{Rotation: hourlyRotation}))
+	assert.NotNil(t, newRotateRule("logx.log", LogConf{MaxSize: 10}))
+}
+
+func TestDefaultLoggerRotateCompress(t *testing.T) {
+	filename := path.Join(t.TempDir(), "logx.log")
+	l, err := newLogger(filename, LogConf{MaxSize: 1, Compress: true})
+	assert.Nil(t, err)
+
+	line := make([]byte, megaBytes)
+	for i := 0; i < 2; i++ {
+		_, err = l.Write(line)
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, l.Close())
+
+	backups, err := filepath.Glob(filename + backupDelimiter + "*")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(backups))
+	assert.True(t, strings.HasSuffix(backups[0], gzipExt))
+
+	fp, err := os.Open(backups[0])
+	assert.Nil(t, err)
+	defer fp.Close()
+	r, err := gzip.NewReader(fp)
+	assert.Nil(t, err)
+	content, err := io.ReadAll(r)
+	assert.Nil(t, err)
+	assert.Equal(t, line, content)
+}
+
+func TestCompressFileFailure(t *testing.T) {
+	file := path.Join(t.TempDir(), "logx.log.2026-10-15")
+	assert.NotNil(t, compressFile(file))
+	assert.False(t, backupExists(file))
+}
+
+func TestPeriodRotateRuleOutdatedFiles(t *testing.T) {
+	dir := t.TempDir()
+	filename := path.Join(dir, "logx.log")
+	now := time.Now()
+	for i := 1; i <= 5; i++ {
+		backup := fmt.Sprintf("%s.2026-10-%02d.gz", filename, i)
+		assert.Nil(t, os.WriteFile(backup, nil, defaultFileMode))
+		modTime := now.Add(-time.Duration(6-i)*hoursPerDay*time.Hour + time.Hour)
+		assert.Nil(t, os.Chtimes(backup, modTime, modTime))
+	}
+
+	rule := &periodRotateRule{filename: filename, layout: dailyLayout, maxBackups: 2}
+	assert.ElementsMatch(t, []string{
+		filename + ".2026-10-01.gz",
+		filename + ".2026-10-02.gz",
+		filename + ".2026-10-03.gz",
+	}, rule.OutdatedFiles())
+
+	rule = &periodRotateRule{filename: filename, layout: dailyLayout, keepDays: 3}
+	assert.ElementsMatch(t, []string{
+		filename + ".2026-10-01.gz",
+		filename + ".2026-10-02.gz",
+	}, rule.OutdatedFiles())
+
+	rule = &periodRotateRule{filename: filename, layout: dailyLayout}
+	assert.Empty(t, rule.OutdatedFiles())
+}
+
+func TestDefaultLoggerWrite(t *testing.T) {
+	filename := path.Join(t.TempDir(), "logx.log")
+	l, err := NewLogger(filename)
+	assert.Nil(t, err)
+
+	_, err = l.Write([]byte("hello\n"))
+	assert.Nil(t, err)
+	assert.Nil(t, l.Close())
+
+	content, err := os.ReadFile(filename)
+	assert.Nil(t, err)
+	assert.Equal(t, "hello\n", string(content))
+
+	_, err = l.Write([]byte("closed\n"))
+	assert.Equal(t, ErrLogFileClosed, err)
+	assert.Equal(t, ErrLogFileClosed, l.Flush())
+	assert.Nil(t, l.Close())
+}
+
+func TestDefaultLoggerWriteCopiesData(t *testing.T) {
+	filename := path.Join(t.TempDir(), "logx.log")
+	l, err := NewLogger(filename)
+	assert.Nil(t, err)
+
+	data := []byte("hello\n")
+	_, err = l.Write(data)
+	assert.Nil(t, err)
+	copy(data, "HELLO\n")
+	assert.Nil(t, l.Close())
+
+	content, err := os.ReadFile(filename)
+	assert.Nil(t, err)
+	assert.Equal(t, "hello\n", string(content))
+}
+
+func TestDefaultLoggerFlush(t *testing.T) {
+	filename := path.Join(t.TempDir(), "logx.log")
+	l, err := NewLogger(filename)
+	assert.Nil(t, err)
+	defer l.Close()
+
+	for i := 0; i < defaultQueueSize*2; i++ {
+		_, err = l.Write([]byte("hello\n"))
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, l.FlushTimeout(time.Second))
+
+	content, err := os.ReadFile(filename)
+	assert.Nil(t, err)
+	assert.Equal(t, defaultQueueSize*2, strings.Count(string(content), "\n"))
+}
+
+func TestDefaultLoggerCloseDrainsConcurrentWriters(t *testing.T) {
+	const (
+		writers = 10
+		lines   = 1000
+	)
+
+	filename := path.Join(t.TempDir(), "logx.log")
+	l, err := NewLogger(filename)
+	assert.Nil(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < lines; j++ {
+				_, _ = l.Write([]byte(fmt.Sprintf("%d-%d\n", i, j)))
+			}
+		}(i)
+	}
+	wg.Wait()
+	assert.Nil(t, l.CloseTimeout(time.Minute))
+
+	content, err := os.ReadFile(filename)
+	assert.Nil(t, err)
+	assert.Equal(t, writers*lines, strings.Count(string(content), "\n"))
+}
+
+func TestDefaultLoggerCloseWhileWriting(t *testing.T) {
+	filename := path.Join(t.TempDir(), "logx.log")
+	l, err := NewLogger(filename)
+	assert.Nil(t, err)
+
+	var (
+		wg       sync.WaitGroup
+		accepted int64
+	)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				if _, err := l.Write([]byte("hello\n")); err != nil {
+					return
+				}
+				atomic.AddInt64(&accepted, 1)
+			}
+		}()
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	assert.Nil(t, l.Close())
+	wg.Wait()
+
+	content, err := os.ReadFile(filename)
+	assert.Nil(t, err)
+	assert.Equal(t, atomic.LoadInt64(&accepted), int64(strings.Count(string(content), "\n")))
+}
+
+func TestDefaultLoggerOverflow(t *testing.T) {
+	tests := []struct {
+		overflow string
+		expect   []string
+	}{
+		{
+			overflow: dropNewestOverflow,
+			expect:   []string{"0", "1"},
+		},
+		{
+			overflow: dropOldestOverflow,
+			expect:   []string{"3", "4"},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.overflow, func(t *testing.T) {
+			// no worker is started, so the queue is never consumed
+			l := &DefaultLogger{
+				asyncQueue: newAsyncQueue(2, test.overflow),
+			}
+			for i := 0; i < 5; i++ {
+				n, err := l.Write([]byte(strconv.Itoa(i)))
+				assert.Nil(t, err)
+				assert.Equal(t, 1, n)
+			}
+
+			assert.Equal(t, uint64(3), l.Dropped())
+			assert.Equal(t, test.expect, []string{string(<-l.channel), string(<-l.channel)})
+		})
+	}
+}
+
+func TestDefaultLoggerQueueSize(t *testing.T) {
+	filename := path.Join(t.TempDir(), "logx.log")
+	l, err := newLogger(filename, LogConf{QueueSize: 10, Overflow: dropNewestOverflow})
+	assert.Nil(t, err)
+	assert.Equal(t, 10, cap(l.channel))
+	assert.Nil(t, l.Close())
+
+	w := &defaultWriter{lw: l}
+	assert.Equal(t, uint64(0), w.Dropped())
+}
+
+func TestDefaultLoggerFlushInterval(t *testing.T) {
+	filename := path.Join(t.TempDir(), "logx.log")
+	l, err := newLogger(filename, LogConf{FlushInterval: 10 * time.Millisecond})
+	assert.Nil(t, err)
+	defer l.Close()
+
+	_, err = l.Write([]byte("hello\n"))
+	assert.Nil(t, err)
+	assert.Eventually(t, func() bool {
+		content, err := os.ReadFile(filename)
+		return err == nil && string(content) == "hello\n"
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestDefaultLoggerWriteWholeEntries(t *testing.T) {
+	filename := path.Join(t.TempDir(), "logx.log")
+	l, err := newLogger(filename, LogConf{BufferSize: 8, FlushInterval: time.Hour})
+	assert.Nil(t, err)
+	defer l.Close()
+
+	for _, entry := range []string{"12345\n", "abcdef\n", "0123456789\n"} {
+		_, err = l.Write([]byte(entry))
+		assert.Nil(t, err)
+	}
+	// the buffer is flushed before an entry not fitting in, the entries larger than the buffer
+	// are written directly, so the file always ends with a whole entry
+	assert.Eventually(t, func() bool {
+		content, err := os.ReadFile(filename)
+		return err == nil && string(content) == "12345\nabcdef\n0123456789\n"
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestDefaultLoggerFlushOnError(t *testing.T) {
+	filename := path.Join(t.TempDir(), "logx.log")
+	l, err := newLogger(filename, LogConf{FlushInterval: time.Hour})
+	assert.Nil(t, err)
+	defer l.Close()
+
+	w := &defaultWriter{lw: l}
+	w.Info("hello")
+	w.Error("failed")
+	assert.Eventually(t, func() bool {
+		content, err := os.ReadFile(filename)
+		return err == nil && strings.Contains(string(content), "hello") &&
+			strings.Contains(string(content), "failed")
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestDefaultLoggerUnbuffered(t *testing.T) {
+	filename := path.Join(t.TempDir(), "logx.log")
+	l, err := newLogger(filename, LogConf{BufferSize: -1})
+	assert.Nil(t, err)
+	assert.Nil(t, l.buf)
+
+	_, err = l.Write([]byte("hello\n"))
+	assert.Nil(t, err)
+	// the entry reaches the file without Flush
+	assert.Eventually(t, func() bool {
+		content, err := os.ReadFile(filename)
+		return err == nil && string(content) == "hello\n"
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Nil(t, l.Close())
+}
+
+func BenchmarkDefaultLoggerUnbuffered(b *testing.B) {
+	benchmarkDefaultLogger(b, LogConf{BufferSize: -1, QueueSize: 1024})
+}
+
+func BenchmarkDefaultLoggerBuffered(b *testing.B) {
+	benchmarkDefaultLogger(b, LogConf{QueueSize: 1024})
+}
+
+func BenchmarkDefaultLoggerBufferedLarge(b *testing.B) {
+	benchmarkDefaultLogger(b, LogConf{BufferSize: 64 << 10, QueueSize: 1024})
+}
+
+func benchmarkDefaultLogger(b *testing.B, c LogConf) {
+	l, err := newLogger(path.Join(b.TempDir(), "logx.log"), c)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	line := []byte(`{"@timestamp":"2026-10-16T10:00:00.000Z","caller":"logx/default_logger_test.go:1","content":"hello there","level":"info"}` + "\n")
+	b.ReportAllocs()
+	b.SetBytes(int64(len(line)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = l.Write(line)
+	}
+	if err := l.Close(); err != nil {
+		b.Fatal(err)
+	}
+}
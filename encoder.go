@@ -0,0 +1,179 @@
+package logx
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	jsonEncoding  = "json"
+	plainEncoding = "plain"
+)
+
+type (
+	// An Entry is a log entry to be encoded.
+	Entry struct {
+		Time    time.Time
+		Level   string
+		Caller  string
+		Content interface{}
+		Fields  []LogField
+	}
+
+	// An Encoder encodes log entries, it must be safe for concurrent use.
+	Encoder interface {
+		// Encode appends entry to buf, without the trailing newline, which is added by the writer.
+		Encode(buf *bytes.Buffer, entry Entry) error
+	}
+
+	// An EncoderFactory creates an Encoder with the settings in c, like TimeFormat.
+	EncoderFactory func(c LogConf) Encoder
+
+	plainEncoder struct {
+		timeFormat string
+		sep        string
+		withColor  bool
+	}
+)
+
+// maxPooledBufferSize limits the buffers kept in bufferPool, so that a huge entry
+// doesn't pin its memory forever.
+const maxPooledBufferSize = 64 << 10
+
+var (
+	bufferPool = sync.Pool{
+		New: func() interface{} {
+			return new(bytes.Buffer)
+		},
+	}
+
+	encodersLock sync.RWMutex
+	encoders     = map[string]EncoderFactory{
+		jsonEncoding: func(c LogConf) Encoder {
+			return &jsonEncoder{
+				timeFormat: timeFormatOf(c),
+				schema:     newJsonSchema(c),
+			}
+		},
+		plainEncoding: func(c LogConf) Encoder {
+			sep := "\t"
+			if len(c.PlainEncodingSep) > 0 {
+				sep = c.PlainEncodingSep
+			}
+
+			return &plainEncoder{
+				timeFormat: timeFormatOf(c),
+				sep:        sep,
+				withColor:  c.WithColor,
+			}
+		},
+		logfmtEncoding: func(c LogConf) Encoder {
+			return &logfmtEncoder{
+				timeFormat: timeFormatOf(c),
+			}
+		},
+	}
+)
+
+// RegisterEncoder 注册名为 name 的编码器，LogConf.Encoding 为 name 时使用 factory 创建编码器，
+// 同名的编码器会被替换
+func RegisterEncoder(name string, factory EncoderFactory) {
+	encodersLock.Lock()
+	defer encodersLock.Unlock()
+	encoders[name] = factory
+}
+
+// getEncoderFactory returns the factory registered with name.
+func getEncoderFactory(name string) (EncoderFactory, bool) {
+	encodersLock.RLock()
+	defer encodersLock.RUnlock()
+	factory, ok := encoders[name]
+	return factory, ok
+}
+
+// encoderNames returns the sorted names of the registered encoders.
+func encoderNames() []string {
+	encodersLock.RLock()
+	defer encodersLock.RUnlock()
+
+	names := make([]string, 0, len(encoders))
+	for name := range encoders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func getBuffer() *bytes.Buffer {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBufferSize {
+		return
+	}
+
+	bufferPool.Put(buf)
+}
+
+// plainValue formats val of the fields, time.Time is formatted without the monotonic clock reading.
+func plainValue(val interface{}) string {
+	if t, ok := val.(time.Time); ok {
+		return t.Format(time.RFC3339Nano)
+	}
+
+	return fmt.Sprint(val)
+}
+
+func timeFormatOf(c LogConf) string {
+	if len(c.TimeFormat) > 0 {
+		return c.TimeFormat
+	}
+
+	return defaultTimeFormat
+}
+
+func (e *plainEncoder) Encode(buf *bytes.Buffer, entry Entry) error {
+	level := entry.Level
+	if e.withColor {
+		level = wrapLevelWithColor(level)
+	}
+
+	buf.WriteString(entry.Time.Format(e.timeFormat))
+	buf.WriteString(e.sep)
+	buf.WriteString(level)
+	buf.WriteString(e.sep)
+
+	switch v := entry.Content.(type) {
+	case string:
+		buf.WriteString(v)
+	case error:
+		buf.WriteString(v.Error())
+	case fmt.Stringer:
+		buf.WriteString(v.String())
+	default:
+		content, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		buf.Write(content)
+	}
+
+	for _, field := range entry.Fields {
+		buf.WriteString(e.sep)
+		buf.WriteString(field.Key)
+		buf.WriteByte('=')
+		buf.WriteString(plainValue(field.Value))
+	}
+	buf.WriteString(e.sep)
+	buf.WriteString(fmt.Sprintf("%s=%s", callerKey, entry.Caller))
+
+	return nil
+}
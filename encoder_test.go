@@ -0,0 +1,93 @@
+package logx
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type upperEncoder struct {
+	prefix string
+}
+
+func (e upperEncoder) Encode(buf *bytes.Buffer, entry Entry) error {
+	buf.WriteString(e.prefix)
+	buf.WriteString(strings.ToUpper(entry.Level))
+	buf.WriteByte(' ')
+	buf.WriteString(strings.ToUpper(fmt.Sprint(entry.Content)))
+	for _, field := range entry.Fields {
+		buf.WriteString(fmt.Sprintf(" %s=%v", field.Key, field.Value))
+	}
+	return nil
+}
+
+func TestRegisterEncoder(t *testing.T) {
+	RegisterEncoder("upper", func(c LogConf) Encoder {
+		return upperEncoder{prefix: c.PlainEncodingSep}
+	})
+	defer func() {
+		encodersLock.Lock()
+		delete(encoders, "upper")
+		encodersLock.Unlock()
+	}()
+
+	assert.Nil(t, LogConf{Encoding: "upper"}.Validate())
+	assert.Contains(t, encoderNames(), "upper")
+
+	var buf bytes.Buffer
+	l, err := New(LogConf{Encoding: "upper", PlainEncodingSep: "> "})
+	assert.Nil(t, err)
+	l.lw.(*defaultWriter).lw = nopCloser{&buf}
+	l.Infow("hello there", String("foo", "bar"))
+	assert.Equal(t, "> INFO HELLO THERE foo=bar\n", buf.String())
+}
+
+func TestEncoderError(t *testing.T) {
+	ec := &encoderConf{encoder: encoderFunc(func(buf *bytes.Buffer, entry Entry) error {
+		return errors.New("encode error")
+	})}
+
+	var buf bytes.Buffer
+	output(&buf, ec, levelInfo, "anything")
+	assert.Equal(t, 0, buf.Len())
+}
+
+func TestPlainEncoder(t *testing.T) {
+	e := encoders[plainEncoding](LogConf{PlainEncodingSep: "|", TimeFormat: time.RFC3339})
+	now := time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC)
+
+	var buf bytes.Buffer
+	assert.Nil(t, e.Encode(&buf, Entry{
+		Time:    now,
+		Level:   levelWarn,
+		Caller:  "logx/encoder_test.go:1",
+		Content: map[string]int{"a": 1},
+		Fields:  []LogField{Int("count", 2)},
+	}))
+	assert.Equal(t, `2026-10-16T08:00:00Z|warn|{"a":1}|count=2|caller=logx/encoder_test.go:1`, buf.String())
+}
+
+func TestNewEncoderConfUnknown(t *testing.T) {
+	ec := newEncoderConf(LogConf{Encoding: "unknown"})
+	_, ok := ec.encoder.(*jsonEncoder)
+	assert.True(t, ok)
+}
+
+type encoderFunc func(buf *bytes.Buffer, entry Entry) error
+
+func (f encoderFunc) Encode(buf *bytes.Buffer, entry Entry) error {
+	return f(buf, entry)
+}
+
+type nopCloser struct {
+	*bytes.Buffer
+}
+
+func (c nopCloser) Close() error {
+	return nil
+}
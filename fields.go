@@ -0,0 +1,48 @@
+package logx
+
+import "time"
+
+// A LogField is a key/value pair written as a top-level field of the log entry.
+type LogField struct {
+	Key   string
+	Value interface{}
+}
+
+// Field 创建日志字段，error 会被转为字符串，其他类型的值由编码器格式化，
+// 例如 time.Time 按 RFC3339Nano 格式输出，time.Duration 和 fmt.Stringer 输出 String() 的结果
+func Field(key string, value interface{}) LogField {
+	if err, ok := value.(error); ok {
+		return LogField{Key: key, Value: err.Error()}
+	}
+
+	return LogField{Key: key, Value: value}
+}
+
+// Any 创建任意类型的日志字段
+func Any(key string, value interface{}) LogField {
+	return Field(key, value)
+}
+
+// Duration 创建 time.Duration 类型的日志字段
+func Duration(key string, value time.Duration) LogField {
+	return LogField{Key: key, Value: value.String()}
+}
+
+// Err 创建 key 为 error 的日志字段
+func Err(err error) LogField {
+	if err == nil {
+		return LogField{Key: errorKey, Value: nil}
+	}
+
+	return LogField{Key: errorKey, Value: err.Error()}
+}
+
+// Int 创建 int 类型的日志字段
+func Int(key string, value int) LogField {
+	return LogField{Key: key, Value: value}
+}
+
+// String 创建 string 类型的日志字段
+func String(key, value string) LogField {
+	return LogField{Key: key, Value: value}
+}
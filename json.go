@@ -0,0 +1,231 @@
+package logx
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+const hex = "0123456789abcdef"
+
+// jsonEncoder encodes entries as json objects, the reserved keys always come first in the order of
+// timestamp, level, caller and content, followed by the fields. The keys are decided by the schema.
+// The common types of values are encoded without reflection, the others fall back to json.Marshal.
+type jsonEncoder struct {
+	timeFormat string
+	schema     jsonSchema
+}
+
+func (e *jsonEncoder) Encode(buf *bytes.Buffer, entry Entry) error {
+	buf.WriteByte('{')
+	writeJsonKey(buf, e.schema.timestampKey)
+	var scratch [64]byte
+	writeJsonBytes(buf, entry.Time.AppendFormat(scratch[:0], e.timeFormat))
+	buf.WriteByte(',')
+	writeJsonKey(buf, e.schema.levelKey)
+	writeJsonString(buf, e.schema.level(entry.Level))
+	buf.WriteByte(',')
+	writeJsonKey(buf, e.schema.callerKey)
+	e.schema.writeCaller(buf, entry.Caller)
+	buf.WriteByte(',')
+	writeJsonKey(buf, e.schema.contentKey)
+	if err := writeJsonValue(buf, entry.Content); err != nil {
+		return err
+	}
+
+	for i, field := range entry.Fields {
+		key := e.schema.field(field.Key)
+		if e.schema.isReservedKey(key) || e.overridden(entry.Fields[i+1:], key) {
+			continue
+		}
+
+		buf.WriteByte(',')
+		writeJsonKey(buf, key)
+		if err := writeJsonValue(buf, field.Value); err != nil {
+			return err
+		}
+	}
+	buf.WriteByte('}')
+
+	return nil
+}
+
+// overridden checks if key is written again by the later fields, only the last value of a key is kept,
+// like the bound fields overridden by the ones passed in the call.
+func (e *jsonEncoder) overridden(later []LogField, key string) bool {
+	for _, field := range later {
+		if e.schema.field(field.Key) == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+func writeJsonKey(buf *bytes.Buffer, key string) {
+	writeJsonString(buf, key)
+	buf.WriteByte(':')
+}
+
+func writeJsonValue(buf *bytes.Buffer, val interface{}) error {
+	var scratch [32]byte
+
+	switch v := val.(type) {
+	case nil:
+		buf.WriteString("null")
+	case string:
+		writeJsonString(buf, v)
+	case bool:
+		buf.Write(strconv.AppendBool(scratch[:0], v))
+	case int:
+		buf.Write(strconv.AppendInt(scratch[:0], int64(v), 10))
+	case int8:
+		buf.Write(strconv.AppendInt(scratch[:0], int64(v), 10))
+	case int16:
+		buf.Write(strconv.AppendInt(scratch[:0], int64(v), 10))
+	case int32:
+		buf.Write(strconv.AppendInt(scratch[:0], int64(v), 10))
+	case int64:
+		buf.Write(strconv.AppendInt(scratch[:0], v, 10))
+	case uint:
+		buf.Write(strconv.AppendUint(scratch[:0], uint64(v), 10))
+	case uint8:
+		buf.Write(strconv.AppendUint(scratch[:0], uint64(v), 10))
+	case uint16:
+		buf.Write(strconv.AppendUint(scratch[:0], uint64(v), 10))
+	case uint32:
+		buf.Write(strconv.AppendUint(scratch[:0], uint64(v), 10))
+	case uint64:
+		buf.Write(strconv.AppendUint(scratch[:0], v, 10))
+	case float32:
+		writeJsonFloat(buf, float64(v), 32)
+	case float64:
+		writeJsonFloat(buf, v, 64)
+	case time.Time:
+		writeJsonBytes(buf, v.AppendFormat(scratch[:0], time.RFC3339Nano))
+	case json.Marshaler:
+		content, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		buf.Write(content)
+	case error:
+		writeJsonString(buf, v.Error())
+	case fmt.Stringer:
+		writeJsonString(buf, v.String())
+	default:
+		content, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		buf.Write(content)
+	}
+
+	return nil
+}
+
+// writeJsonFloat writes f like encoding/json does, NaN and infinities are written as strings
+// since json has no representation for them.
+func writeJsonFloat(buf *bytes.Buffer, f float64, bits int) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		writeJsonString(buf, strconv.FormatFloat(f, 'g', -1, bits))
+		return
+	}
+
+	var scratch [32]byte
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 {
+		if bits == 64 && (abs < 1e-6 || abs >= 1e21) || bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+
+	b := strconv.AppendFloat(scratch[:0], f, format, -1, bits)
+	if format == 'e' {
+		// clean up e-09 to e-9
+		n := len(b)
+		if n >= 4 && b[n-4] == 'e' && b[n-3] == '-' && b[n-2] == '0' {
+			b[n-2] = b[n-1]
+			b = b[:n-1]
+		}
+	}
+	buf.Write(b)
+}
+
+// writeJsonBytes writes b as a json string, b is usually ascii that needs no escaping.
+func writeJsonBytes(buf *bytes.Buffer, b []byte) {
+	for _, c := range b {
+		if c < utf8.RuneSelf && c >= ' ' && c != '"' && c != '\\' {
+			continue
+		}
+
+		writeJsonString(buf, string(b))
+		return
+	}
+
+	buf.WriteByte('"')
+	buf.Write(b)
+	buf.WriteByte('"')
+}
+
+// writeJsonString writes s as a quoted json string, escaped the same way as encoding/json,
+// except that <, > and & are kept as is. Invalid utf-8 is replaced with U+FFFD.
+func writeJsonString(buf *bytes.Buffer, s string) {
+	buf.WriteByte('"')
+	start := 0
+	for i := 0; i < len(s); {
+		if c := s[i]; c < utf8.RuneSelf {
+			if c >= ' ' && c != '"' && c != '\\' {
+				i++
+				continue
+			}
+
+			buf.WriteString(s[start:i])
+			switch c {
+			case '"', '\\':
+				buf.WriteByte('\\')
+				buf.WriteByte(c)
+			case '\n':
+				buf.WriteString(`\n`)
+			case '\r':
+				buf.WriteString(`\r`)
+			case '\t':
+				buf.WriteString(`\t`)
+			default:
+				buf.WriteString(`\u00`)
+				buf.WriteByte(hex[c>>4])
+				buf.WriteByte(hex[c&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			buf.WriteString(s[start:i])
+			buf.WriteString(`\ufffd`)
+			i += size
+			start = i
+			continue
+		}
+
+		// U+2028 and U+2029 are valid json, but break javascript
+		if r == '\u2028' || r == '\u2029' {
+			buf.WriteString(s[start:i])
+			buf.WriteString(`\u202`)
+			buf.WriteByte(hex[r&0xf])
+			i += size
+			start = i
+			continue
+		}
+
+		i += size
+	}
+	buf.WriteString(s[start:])
+	buf.WriteByte('"')
+}
@@ -0,0 +1,182 @@
+package logx
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJsonEncoderOrder(t *testing.T) {
+	e := encoders[jsonEncoding](LogConf{TimeFormat: time.RFC3339})
+	now := time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC)
+
+	var buf bytes.Buffer
+	assert.Nil(t, e.Encode(&buf, Entry{
+		Time:    now,
+		Level:   levelInfo,
+		Caller:  "logx/json_test.go:1",
+		Content: "hello there",
+		Fields: []LogField{
+			String("foo", "bar"),
+			Int("count", 3),
+			String(levelKey, "ignored"),
+			Any("nested", map[string]int{"a": 1}),
+			Any("ok", true),
+			Any("nil", nil),
+			Any("ratio", 0.5),
+		},
+	}))
+	assert.Equal(t, `{"@timestamp":"2026-10-16T08:00:00Z","level":"info","caller":"logx/json_test.go:1",`+
+		`"content":"hello there","foo":"bar","count":3,"nested":{"a":1},"ok":true,"nil":null,"ratio":0.5}`,
+		buf.String())
+}
+
+func TestJsonEncoderDuplicateKeys(t *testing.T) {
+	e := encoders[jsonEncoding](LogConf{Schema: ecsSchema})
+	var buf bytes.Buffer
+	assert.Nil(t, e.Encode(&buf, Entry{
+		Fields: []LogField{
+			String("k", "1"),
+			String("error.message", "bound"),
+			Int("n", 1),
+			String("k", "2"),
+			String(errorKey, "boom"),
+		},
+	}))
+
+	var entry map[string]interface{}
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "2", entry["k"])
+	assert.Equal(t, "boom", entry["error.message"])
+	assert.Equal(t, 1, strings.Count(buf.String(), `"k":`))
+	assert.Equal(t, 1, strings.Count(buf.String(), `"error.message":`))
+	assert.True(t, strings.HasSuffix(buf.String(), `"n":1,"k":"2","error.message":"boom"}`))
+}
+
+func TestJsonEncoderError(t *testing.T) {
+	e := encoders[jsonEncoding](LogConf{})
+	var buf bytes.Buffer
+	assert.NotNil(t, e.Encode(&buf, Entry{Content: make(chan int)}))
+	assert.NotNil(t, e.Encode(&buf, Entry{Fields: []LogField{Any("ch", make(chan int))}}))
+}
+
+func TestWriteJsonString(t *testing.T) {
+	tests := []string{
+		"",
+		"hello there",
+		`say "hi" \ bye`,
+		"a\nb\rc\td",
+		"\x00\x01\x1f\x7f",
+		"<html>&amp;",
+		"中文 日本語",
+		"line\u2028sep\u2029",
+		"bad \xff\xfe utf8",
+	}
+
+	for _, test := range tests {
+		var buf bytes.Buffer
+		writeJsonString(&buf, test)
+
+		var decoded string
+		assert.Nil(t, json.Unmarshal(buf.Bytes(), &decoded), buf.String())
+
+		expect, err := json.Marshal(test)
+		assert.Nil(t, err)
+		var expectDecoded string
+		assert.Nil(t, json.Unmarshal(expect, &expectDecoded))
+		assert.Equal(t, expectDecoded, decoded)
+	}
+}
+
+func TestWriteJsonValue(t *testing.T) {
+	tests := []interface{}{
+		int8(-1), int16(2), int32(-3), int64(4), uint(5), uint8(6), uint16(7), uint32(8), uint64(9),
+		float32(1.5), 1e-7, 1e21, 123456789.125, 0.0, -2.5e-10,
+		false, []string{"a"}, struct{ A int }{A: 1}, json.RawMessage(`{"x":1}`),
+	}
+
+	for _, test := range tests {
+		var buf bytes.Buffer
+		assert.Nil(t, writeJsonValue(&buf, test))
+		expect, err := json.Marshal(test)
+		assert.Nil(t, err)
+		assert.Equal(t, string(expect), buf.String())
+	}
+
+	var buf bytes.Buffer
+	assert.Nil(t, writeJsonValue(&buf, math.Inf(1)))
+	assert.Equal(t, `"+Inf"`, buf.String())
+
+	buf.Reset()
+	assert.Nil(t, writeJsonValue(&buf, errors.New("boom")))
+	assert.Equal(t, `"boom"`, buf.String())
+
+	buf.Reset()
+	assert.Nil(t, writeJsonValue(&buf, time.Minute))
+	assert.Equal(t, `"1m0s"`, buf.String())
+
+	buf.Reset()
+	now := time.Now()
+	assert.Nil(t, writeJsonValue(&buf, now))
+	expect, err := json.Marshal(now)
+	assert.Nil(t, err)
+	assert.Equal(t, string(expect), buf.String())
+}
+
+func BenchmarkJsonEncoder(b *testing.B) {
+	benchmarkEncoder(b, encoders[jsonEncoding](LogConf{}))
+}
+
+func BenchmarkJsonMapMarshal(b *testing.B) {
+	benchmarkEncoder(b, encoderFunc(func(buf *bytes.Buffer, entry Entry) error {
+		m := make(map[string]interface{}, len(entry.Fields)+4)
+		for _, field := range entry.Fields {
+			m[field.Key] = field.Value
+		}
+		m[timestampKey] = entry.Time.Format(defaultTimeFormat)
+		m[levelKey] = entry.Level
+		m[contentKey] = entry.Content
+		m[callerKey] = entry.Caller
+
+		content, err := json.Marshal(m)
+		if err != nil {
+			return err
+		}
+		buf.Write(content)
+		return nil
+	}))
+}
+
+func BenchmarkOutputJson(b *testing.B) {
+	b.ReportAllocs()
+	fields := []LogField{String("foo", "bar"), Int("count", 3)}
+	for i := 0; i < b.N; i++ {
+		output(io.Discard, defaultEncoderConf, levelInfo, "hello there", fields...)
+	}
+}
+
+func benchmarkEncoder(b *testing.B, e Encoder) {
+	entry := Entry{
+		Time:    time.Now(),
+		Level:   levelInfo,
+		Caller:  "logx/json_test.go:1",
+		Content: "hello there",
+		Fields:  []LogField{String("foo", "bar"), Int("count", 3), Any("ok", true)},
+	}
+
+	b.ReportAllocs()
+	var buf bytes.Buffer
+	for i := 0; i < b.N; i++ {
+		buf.Reset()
+		if err := e.Encode(&buf, entry); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package logx
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	logfmtEncoding = "logfmt"
+
+	logfmtTimeKey    = "ts"
+	logfmtMessageKey = "msg"
+)
+
+// logfmtEncoder encodes entries as logfmt, like ts=... level=info msg="hello there" caller=...,
+// structured values are flattened into nested keys, like user.name=foo. Like jsonEncoder, the fields
+// with the reserved keys are skipped, and only the last value of a key is kept.
+type logfmtEncoder struct {
+	timeFormat string
+}
+
+func (e *logfmtEncoder) Encode(buf *bytes.Buffer, entry Entry) error {
+	writeLogfmtPair(buf, logfmtTimeKey, entry.Time.Format(e.timeFormat))
+	buf.WriteByte(' ')
+	writeLogfmtPair(buf, levelKey, entry.Level)
+	buf.WriteByte(' ')
+
+	msg, err := logfmtMessage(entry.Content)
+	if err != nil {
+		return err
+	}
+	writeLogfmtPair(buf, logfmtMessageKey, msg)
+	buf.WriteByte(' ')
+	writeLogfmtPair(buf, callerKey, entry.Caller)
+
+	for i, field := range entry.Fields {
+		key := logfmtKey(field.Key)
+		if isLogfmtReservedKey(key) || logfmtOverridden(entry.Fields[i+1:], key) {
+			continue
+		}
+
+		if err := writeLogfmtValue(buf, key, field.Value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func isLogfmtReservedKey(key string) bool {
+	switch key {
+	case logfmtTimeKey, levelKey, logfmtMessageKey, callerKey:
+		return true
+	default:
+		return false
+	}
+}
+
+// logfmtOverridden checks if key is written again by the later fields.
+func logfmtOverridden(later []LogField, key string) bool {
+	for _, field := range later {
+		if logfmtKey(field.Key) == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+func logfmtMessage(val interface{}) (string, error) {
+	switch v := val.(type) {
+	case string:
+		return v, nil
+	case error:
+		return v.Error(), nil
+	case fmt.Stringer:
+		return v.String(), nil
+	default:
+		content, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return string(content), nil
+	}
+}
+
+// writeLogfmtValue writes val with key, composite values are written as nested keys.
+func writeLogfmtValue(buf *bytes.Buffer, key string, val interface{}) error {
+	switch v := val.(type) {
+	case nil:
+		buf.WriteByte(' ')
+		writeLogfmtPair(buf, key, "null")
+	case string:
+		buf.WriteByte(' ')
+		writeLogfmtPair(buf, key, v)
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		buf.WriteByte(' ')
+		writeLogfmtPair(buf, key, fmt.Sprint(v))
+	case time.Time:
+		buf.WriteByte(' ')
+		writeLogfmtPair(buf, key, v.Format(time.RFC3339Nano))
+	case error:
+		buf.WriteByte(' ')
+		writeLogfmtPair(buf, key, v.Error())
+	case fmt.Stringer:
+		buf.WriteByte(' ')
+		writeLogfmtPair(buf, key, v.String())
+	case map[string]interface{}:
+		// keep the key of the empty maps, which have no nested keys
+		if len(v) == 0 {
+			buf.WriteByte(' ')
+			writeLogfmtPair(buf, key, "{}")
+			return nil
+		}
+
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			if err := writeLogfmtValue(buf, key+"."+k, v[k]); err != nil {
+				return err
+			}
+		}
+	default:
+		// other values like structs and maps are flattened by their json form
+		content, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+
+		var decoded interface{}
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.UseNumber()
+		if err = decoder.Decode(&decoded); err != nil {
+			return err
+		}
+
+		switch d := decoded.(type) {
+		case map[string]interface{}:
+			return writeLogfmtValue(buf, key, d)
+		case string:
+			buf.WriteByte(' ')
+			writeLogfmtPair(buf, key, d)
+		default:
+			buf.WriteByte(' ')
+			writeLogfmtPair(buf, key, string(content))
+		}
+	}
+
+	return nil
+}
+
+func writeLogfmtPair(buf *bytes.Buffer, key, val string) {
+	buf.WriteString(logfmtKey(key))
+	buf.WriteByte('=')
+	if needsLogfmtQuote(val) {
+		buf.WriteString(strconv.Quote(val))
+	} else {
+		buf.WriteString(val)
+	}
+}
+
+// logfmtKey replaces the characters not allowed in logfmt keys with underscores.
+func logfmtKey(key string) string {
+	if len(key) == 0 {
+		return "_"
+	}
+
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError || !unicode.IsPrint(r) {
+			return '_'
+		}
+		return r
+	}, key)
+}
+
+func needsLogfmtQuote(val string) bool {
+	if len(val) == 0 {
+		return true
+	}
+
+	for _, r := range val {
+		if r <= ' ' || r == '=' || r == '"' || r == '\\' || r == utf8.RuneError || !unicode.IsPrint(r) {
+			return true
+		}
+	}
+
+	return false
+}
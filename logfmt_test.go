@@ -0,0 +1,98 @@
+package logx
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogfmtEncoder(t *testing.T) {
+	e := encoders[logfmtEncoding](LogConf{TimeFormat: time.RFC3339})
+	now := time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC)
+
+	var buf bytes.Buffer
+	assert.Nil(t, e.Encode(&buf, Entry{
+		Time:    now,
+		Level:   levelInfo,
+		Caller:  "logx/logfmt_test.go:1",
+		Content: "hello there",
+		Fields: []LogField{
+			String("empty", ""),
+			String("quote", `say "hi"`),
+			String("multi line", "a\nb"),
+			Int("count", 3),
+			Duration("elapsed", time.Second),
+			Err(errors.New("boom")),
+			Any("pair", "a=b"),
+		},
+	}))
+	assert.Equal(t, `ts=2026-10-16T08:00:00Z level=info msg="hello there" caller=logx/logfmt_test.go:1`+
+		` empty="" quote="say \"hi\"" multi_line="a\nb" count=3 elapsed=1s error=boom pair="a=b"`, buf.String())
+}
+
+func TestLogfmtEncoderNested(t *testing.T) {
+	type user struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+
+	e := encoders[logfmtEncoding](LogConf{})
+	var buf bytes.Buffer
+	assert.Nil(t, e.Encode(&buf, Entry{
+		Time:    time.Now(),
+		Level:   levelWarn,
+		Content: map[string]int{"a": 1},
+		Fields: []LogField{
+			Any("user", user{Name: "foo bar", Age: 18}),
+			Any("meta", map[string]interface{}{"b": nil, "a": map[string]interface{}{"c": true}}),
+			Any("tags", []string{"x", "y"}),
+		},
+	}))
+	assert.Contains(t, buf.String(), ` msg="{\"a\":1}" caller=""`)
+	assert.Contains(t, buf.String(), ` user.age=18 user.name="foo bar"`)
+	assert.Contains(t, buf.String(), ` meta.a.c=true meta.b=null`)
+	assert.Contains(t, buf.String(), ` tags="[\"x\",\"y\"]"`)
+
+	buf.Reset()
+	assert.NotNil(t, e.Encode(&buf, Entry{Content: make(chan int)}))
+	assert.NotNil(t, e.Encode(&buf, Entry{Fields: []LogField{Any("ch", make(chan int))}}))
+}
+
+func TestLogfmtEncoderDuplicateKeys(t *testing.T) {
+	e := encoders[logfmtEncoding](LogConf{TimeFormat: time.RFC3339})
+	var buf bytes.Buffer
+	assert.Nil(t, e.Encode(&buf, Entry{
+		Time:    time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC),
+		Level:   levelInfo,
+		Content: "dup",
+		Fields: []LogField{
+			String("level", "bad"),
+			String("msg", "bad"),
+			String("ts", "bad"),
+			String("caller", "bad"),
+			String("a b", "first"),
+			Int("n", 1),
+			String("a_b", "last"),
+			Any("empty", map[string]interface{}{}),
+			Any("nested", map[string]interface{}{"m": map[string]int{}}),
+		},
+	}))
+	assert.Equal(t, `ts=2026-10-16T08:00:00Z level=info msg=dup caller=""`+
+		` n=1 a_b=last empty={} nested.m={}`, buf.String())
+}
+
+func TestLogfmtOutput(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := New(LogConf{Encoding: logfmtEncoding})
+	assert.Nil(t, err)
+	l.lw.(*defaultWriter).lw = nopCloser{&buf}
+
+	file, line := getFileLine()
+	l.Infow("hello there", String("foo", "bar"))
+	assert.Contains(t, buf.String(), ` level=info msg="hello there" caller=`)
+	assert.Contains(t, buf.String(), fmt.Sprintf("%s:%d foo=bar\n", file, line+1))
+}
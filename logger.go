@@ -1,11 +1,39 @@
 package logx
 
+import "context"
+
 type Logger interface {
+	Debug(...interface{})
+
+	Debugf(string, ...interface{})
+
+	Debugw(string, ...LogField)
+
 	Error(...interface{})
 
 	Errorf(string, ...interface{})
 
+	Errorw(string, ...LogField)
+
+	Fatal(...interface{})
+
+	Fatalf(string, ...interface{})
+
+	Fatalw(string, ...LogField)
+
 	Info(...interface{})
 
 	Infof(string, ...interface{})
+
+	Infow(string, ...LogField)
+
+	Warn(...interface{})
+
+	Warnf(string, ...interface{})
+
+	Warnw(string, ...LogField)
+
+	With(...LogField) Logger
+
+	WithContext(context.Context) Logger
 }
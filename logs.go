@@ -4,70 +4,93 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"os"
 	"runtime/debug"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 const (
-	InfoLevel uint32 = iota
+	DebugLevel uint32 = iota
+	InfoLevel
+	WarnLevel
 	ErrorLevel
+	FatalLevel
 )
 
-const (
-	jsonEncodingType = iota
-	plainEncodingType
-
-	plainEncoding = "plain"
-
-	fileMode = "file"
-)
+const fileMode = "file"
 
 type logger struct {
-	lw Writer
+	// lw 为 nil 时使用全局 writer
+	lw     Writer
+	fields []LogField
 }
 
 type (
 	LogConf struct {
-		Mode             string `json:",default=console,options=[console,file]"`
-		Encoding         string `json:",default=json,options=[json,plain]"`
-		PlainEncodingSep string `json:",default=\t,optional"`
-		WithColor        bool   `json:",default=false,optional"`
-		TimeFormat       string `json:",optional"`
-		Path             string `json:",default=logs"`
-		Level            string `json:",default=info,options=[info,error]"`
+		Mode             string        `json:",default=console,options=[console,file,syslog]"`
+		Encoding         string        `json:",default=json"`
+		PlainEncodingSep string        `json:",default=\t,optional"`
+		WithColor        bool          `json:",default=false,optional"`
+		TimeFormat       string        `json:",optional"`
+		Schema           string        `json:",optional,options=[ecs,gcp,datadog]"`
+		TimestampKey     string        `json:",optional"`
+		LevelKey         string        `json:",optional"`
+		CallerKey        string        `json:",optional"`
+		ContentKey       string        `json:",optional"`
+		Path             string        `json:",default=logs"`
+		Filename         string        `json:",optional"`
+		Level            string        `json:",default=info,options=[debug,info,warn,error,fatal]"`
+		Rotation         string        `json:",default=none,options=[none,daily,hourly]"`
+		MaxSize          int           `json:",default=0"`
+		KeepDays         int           `json:",default=0"`
+		MaxBackups       int           `json:",default=0"`
+		Compress         bool          `json:",default=false,optional"`
+		QueueSize        int           `json:",default=100"`
+		Overflow         string        `json:",default=block,options=[block,drop_newest,drop_oldest]"`
+		BufferSize       int           `json:",default=4096"`
+		FlushInterval    time.Duration `json:",default=1s"`
+		SingleStream     bool          `json:",default=false,optional"`
+		SplitByLevel     bool          `json:",default=false,optional"`
+		KeepCombined     bool          `json:",default=false,optional"`
+		Network          string        `json:",optional,options=[unix,unixgram,udp,tcp]"`
+		Address          string        `json:",optional"`
+		AppName          string        `json:",optional"`
+		Facility         string        `json:",default=user,options=[kern,user,mail,daemon,auth,syslog,lpr,news,uucp,cron,authpriv,ftp,local0,local1,local2,local3,local4,local5,local6,local7]"`
+		Spool            bool          `json:",default=false,optional"`
+		MaxSpoolSize     int           `json:",default=100"`
 	}
 )
 
 var (
-	setupOnce        sync.Once
-	logLevel         uint32
-	encoding         uint32 = jsonEncodingType
-	withColor               = false
-	plainEncodingSep        = "\t"
-	timeFormat              = "2006-01-02T15:04:05.000Z07:00"
-	writer                  = new(atomicWriter)
-	conf                    = new(LogConf)
+	setupOnce          sync.Once
+	logLevel           = InfoLevel
+	encoderSettings    atomic.Value
+	defaultEncoderConf = newEncoderConf(LogConf{})
+	writer             = new(atomicWriter)
+	confLock           sync.RWMutex
+	conf               = new(LogConf)
+	exit               = os.Exit
 )
 
 // Load 加载日志配置
 func Load(c LogConf) (err error) {
+	// Invalid configs are rejected before setupOnce, so that a typo doesn't block the later calls.
+	if err = c.Validate(); err != nil {
+		return err
+	}
+
 	// Just ignore the subsequent SetUp calls.
 	// Because multiple services in one process might call SetUp respectively.
 	// Need to wait for the first caller to complete the execution.
 	setupOnce.Do(func() {
-		conf = &c
+		storeConf(c)
 
 		setupLogLevel(c)
 
 		setupPath(c)
 
-		setupTimeFormat(c)
-
-		setupPlainEncodingSep(c)
-
-		setupWithColor(c)
-
 		setupEncoding(c)
 
 		err = setupWriter(c)
@@ -76,12 +99,63 @@ func Load(c LogConf) (err error) {
 	return
 }
 
+// Reload 在运行时重新应用日志配置，不受 Load 只执行一次的限制。
+// 新的 writer 创建成功后才会替换旧的 writer，旧的 writer 等正在写入的日志完成、写完队列中的日志后关闭
+func Reload(c LogConf) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+
+	confLock.Lock()
+	defer confLock.Unlock()
+
+	w, err := newModeWriter(c)
+	if err != nil {
+		return err
+	}
+
+	// the subsequent Load calls are ignored, as if Load is called with c
+	setupOnce.Do(func() {})
+	conf = &c
+
+	setupLogLevel(c)
+
+	setupEncoding(c)
+
+	if old := writer.Swap(w); old != nil {
+		return old.Close()
+	}
+
+	return nil
+}
+
+// New 根据 c 创建独立的 Logger，编码方式、日志级别等配置只作用于该 Logger，不影响全局配置。
+// file 模式下日志文件已被其他 Logger 打开时返回错误，可以通过 Path 或 Filename 使用其他文件
+func New(c LogConf) (*logger, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	w, err := newScopedWriter(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logger{
+		lw: w,
+	}, nil
+}
+
 // NewFileLogger 创建新的 file 日志记录
 func NewFileLogger(filename string) (*logger, error) {
-	if conf == nil {
+	c := loadConf()
+	if c == nil {
 		return nil, errors.New("config not set")
 	}
-	w, err := newFileWriter(*conf, filename)
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	w, err := newFileWriter(*c, filename)
 	if err != nil {
 		return nil, err
 	}
@@ -90,52 +164,305 @@ func NewFileLogger(filename string) (*logger, error) {
 	}, nil
 }
 
+// Debug 记录 Debug 级别日志
+func (l *logger) Debug(v ...interface{}) {
+	w, h := l.hold()
+	defer h.release()
+	debugTextSync(w, fmt.Sprint(v...), l.fields...)
+}
+
+// Debugf 格式化并记录 Debug 级别日志
+func (l *logger) Debugf(format string, v ...interface{}) {
+	w, h := l.hold()
+	defer h.release()
+	debugTextSync(w, fmt.Sprintf(format, v...), l.fields...)
+}
+
+// Debugw 记录带字段的 Debug 级别日志
+func (l *logger) Debugw(msg string, fields ...LogField) {
+	w, h := l.hold()
+	defer h.release()
+	debugTextSync(w, msg, l.withFields(fields)...)
+}
+
 // Error 记录 Error 级别日志
 func (l *logger) Error(v ...interface{}) {
-	errorTextSync(l.lw, fmt.Sprint(v...))
+	w, h := l.hold()
+	defer h.release()
+	errorTextSync(w, fmt.Sprint(v...), l.fields...)
 }
 
 // Errorf 格式化并记录 Error 级别日志
 func (l *logger) Errorf(format string, v ...interface{}) {
-	errorTextSync(l.lw, fmt.Errorf(format, v...).Error())
+	w, h := l.hold()
+	defer h.release()
+	errorTextSync(w, fmt.Errorf(format, v...).Error(), l.fields...)
+}
+
+// Errorw 记录带字段的 Error 级别日志
+func (l *logger) Errorw(msg string, fields ...LogField) {
+	w, h := l.hold()
+	defer h.release()
+	errorTextSync(w, msg, l.withFields(fields)...)
 }
 
 // Info 记录 Info 级别日志
 func (l *logger) Info(v ...interface{}) {
-	infoTextSync(l.lw, fmt.Sprint(v...))
+	w, h := l.hold()
+	defer h.release()
+	infoTextSync(w, fmt.Sprint(v...), l.fields...)
 }
 
 // Infof 格式化并记录 Info 级别日志
 func (l *logger) Infof(format string, v ...interface{}) {
-	infoTextSync(l.lw, fmt.Sprintf(format, v...))
+	w, h := l.hold()
+	defer h.release()
+	infoTextSync(w, fmt.Sprintf(format, v...), l.fields...)
+}
+
+// Infow 记录带字段的 Info 级别日志
+func (l *logger) Infow(msg string, fields ...LogField) {
+	w, h := l.hold()
+	defer h.release()
+	infoTextSync(w, msg, l.withFields(fields)...)
+}
+
+// Warn 记录 Warn 级别日志
+func (l *logger) Warn(v ...interface{}) {
+	w, h := l.hold()
+	defer h.release()
+	warnTextSync(w, fmt.Sprint(v...), l.fields...)
+}
+
+// Warnf 格式化并记录 Warn 级别日志
+func (l *logger) Warnf(format string, v ...interface{}) {
+	w, h := l.hold()
+	defer h.release()
+	warnTextSync(w, fmt.Sprintf(format, v...), l.fields...)
+}
+
+// Warnw 记录带字段的 Warn 级别日志
+func (l *logger) Warnw(msg string, fields ...LogField) {
+	w, h := l.hold()
+	defer h.release()
+	warnTextSync(w, msg, l.withFields(fields)...)
+}
+
+// Fatal 记录 Fatal 级别日志，关闭 writer 后退出进程
+func (l *logger) Fatal(v ...interface{}) {
+	w, h := l.hold()
+	defer h.release()
+	fatalTextSync(w, fmt.Sprint(v...), l.fields...)
+}
+
+// Fatalf 格式化并记录 Fatal 级别日志，关闭 writer 后退出进程
+func (l *logger) Fatalf(format string, v ...interface{}) {
+	w, h := l.hold()
+	defer h.release()
+	fatalTextSync(w, fmt.Sprintf(format, v...), l.fields...)
+}
+
+// Fatalw 记录带字段的 Fatal 级别日志，关闭 writer 后退出进程
+func (l *logger) Fatalw(msg string, fields ...LogField) {
+	w, h := l.hold()
+	defer h.release()
+	fatalTextSync(w, msg, l.withFields(fields)...)
+}
+
+// With 返回绑定了字段的子 Logger，子 Logger 与 l 共用同一个 writer，
+// 每条日志都会带上绑定的字段
+func (l *logger) With(fields ...LogField) Logger {
+	bound := make([]LogField, 0, len(l.fields)+len(fields))
+	bound = append(bound, l.fields...)
+	return &logger{
+		lw:     l.lw,
+		fields: append(bound, fields...),
+	}
 }
 
-// Close 关闭
+// Close 关闭，子 Logger 与父 Logger 共用 writer，关闭任意一个都会关闭该 writer
 func (l *logger) Close() error {
+	if l.lw == nil {
+		return nil
+	}
+
 	return l.lw.(io.Closer).Close()
 }
 
+// Flush 将已写入的日志刷新到文件
+func (l *logger) Flush() error {
+	return flushWriter(l.writer())
+}
+
+// Dropped 返回因队列已满被丢弃的日志条数
+func (l *logger) Dropped() uint64 {
+	if c, ok := l.writer().(dropCounter); ok {
+		return c.Dropped()
+	}
+
+	return 0
+}
+
+// hold returns the writer of l, the global writer is held until released, see holdWriter.
+func (l *logger) hold() (Writer, *heldWriter) {
+	if l.lw != nil {
+		return l.lw, nil
+	}
+
+	h := holdWriter()
+	return h.writer, h
+}
+
+func (l *logger) writer() Writer {
+	if l.lw != nil {
+		return l.lw
+	}
+
+	return getWriter()
+}
+
+// withFields 返回绑定字段在前、fields 在后的新切片，避免子 Logger 之间共享底层数组
+func (l *logger) withFields(fields []LogField) []LogField {
+	if len(l.fields) == 0 {
+		return fields
+	}
+
+	merged := make([]LogField, 0, len(l.fields)+len(fields))
+	merged = append(merged, l.fields...)
+	return append(merged, fields...)
+}
+
+// Debug 记录 Debug 级别日志
+func Debug(v ...interface{}) {
+	h := holdWriter()
+	defer h.release()
+	debugTextSync(h.writer, fmt.Sprint(v...))
+}
+
+// Debugf 格式化并记录 Debug 级别日志
+func Debugf(format string, v ...interface{}) {
+	h := holdWriter()
+	defer h.release()
+	debugTextSync(h.writer, fmt.Sprintf(format, v...))
+}
+
+// Debugw 记录带字段的 Debug 级别日志
+func Debugw(msg string, fields ...LogField) {
+	h := holdWriter()
+	defer h.release()
+	debugTextSync(h.writer, msg, fields...)
+}
+
 // Error 记录 Error 级别日志
 func Error(v ...interface{}) {
-	errorTextSync(getWriter(), fmt.Sprint(v...))
+	h := holdWriter()
+	defer h.release()
+	errorTextSync(h.writer, fmt.Sprint(v...))
 }
 
 // Errorf 格式化并记录 Error 级别日志
 func Errorf(format string, v ...interface{}) {
-	errorTextSync(getWriter(), fmt.Errorf(format, v...).Error())
+	h := holdWriter()
+	defer h.release()
+	errorTextSync(h.writer, fmt.Errorf(format, v...).Error())
+}
+
+// Errorw 记录带字段的 Error 级别日志
+func Errorw(msg string, fields ...LogField) {
+	h := holdWriter()
+	defer h.release()
+	errorTextSync(h.writer, msg, fields...)
 }
 
 // Info 记录 Info 级别日志
 func Info(v ...interface{}) {
-	infoTextSync(getWriter(), fmt.Sprint(v...))
+	h := holdWriter()
+	defer h.release()
+	infoTextSync(h.writer, fmt.Sprint(v...))
 }
 
 // Infof 格式化并记录 Info 级别日志
 func Infof(format string, v ...interface{}) {
-	infoTextSync(getWriter(), fmt.Sprintf(format, v...))
+	h := holdWriter()
+	defer h.release()
+	infoTextSync(h.writer, fmt.Sprintf(format, v...))
+}
+
+// Infow 记录带字段的 Info 级别日志
+func Infow(msg string, fields ...LogField) {
+	h := holdWriter()
+	defer h.release()
+	infoTextSync(h.writer, msg, fields...)
 }
 
-// Close 关闭
+// Warn 记录 Warn 级别日志
+func Warn(v ...interface{}) {
+	h := holdWriter()
+	defer h.release()
+	warnTextSync(h.writer, fmt.Sprint(v...))
+}
+
+// Warnf 格式化并记录 Warn 级别日志
+func Warnf(format string, v ...interface{}) {
+	h := holdWriter()
+	defer h.release()
+	warnTextSync(h.writer, fmt.Sprintf(format, v...))
+}
+
+// Warnw 记录带字段的 Warn 级别日志
+func Warnw(msg string, fields ...LogField) {
+	h := holdWriter()
+	defer h.release()
+	warnTextSync(h.writer, msg, fields...)
+}
+
+// Fatal 记录 Fatal 级别日志，关闭 writer 后退出进程
+func Fatal(v ...interface{}) {
+	h := holdWriter()
+	defer h.release()
+	fatalTextSync(h.writer, fmt.Sprint(v...))
+}
+
+// Fatalf 格式化并记录 Fatal 级别日志，关闭 writer 后退出进程
+func Fatalf(format string, v ...interface{}) {
+	h := holdWriter()
+	defer h.release()
+	fatalTextSync(h.writer, fmt.Sprintf(format, v...))
+}
+
+// Fatalw 记录带字段的 Fatal 级别日志，关闭 writer 后退出进程
+func Fatalw(msg string, fields ...LogField) {
+	h := holdWriter()
+	defer h.release()
+	fatalTextSync(h.writer, msg, fields...)
+}
+
+// With 返回绑定了字段的 Logger，日志写入全局 writer
+func With(fields ...LogField) Logger {
+	return &logger{
+		fields: append([]LogField(nil), fields...),
+	}
+}
+
+// Flush 将已写入的日志刷新到文件，writer 不支持时直接返回
+func Flush() error {
+	if w := writer.Load(); w != nil {
+		return flushWriter(w)
+	}
+
+	return nil
+}
+
+// Dropped 返回因队列已满被丢弃的日志条数，writer 不支持时返回 0
+func Dropped() uint64 {
+	if c, ok := writer.Load().(dropCounter); ok {
+		return c.Dropped()
+	}
+
+	return 0
+}
+
+// Close 关闭，关闭前会等待正在写入和已写入的日志全部落盘
 func Close() error {
 	if w := writer.Swap(nil); w != nil {
 		return w.(io.Closer).Close()
@@ -144,29 +471,87 @@ func Close() error {
 	return nil
 }
 
+// debugTextSync 写入 Debug 级别日志
+func debugTextSync(w Writer, msg string, fields ...LogField) {
+	if shallLogTo(w, DebugLevel) {
+		w.Debug(msg, fields...)
+	}
+}
+
 // errorTextSync 写入 Error 级别日志
-func errorTextSync(w Writer, msg string) {
-	if shallLog(ErrorLevel) {
-		w.Error(fmt.Sprintf("%s\n%s", msg, string(debug.Stack())))
+func errorTextSync(w Writer, msg string, fields ...LogField) {
+	if shallLogTo(w, ErrorLevel) {
+		w.Error(fmt.Sprintf("%s\n%s", msg, string(debug.Stack())), fields...)
 	}
 }
 
+// fatalTextSync 写入 Fatal 级别日志，关闭 writer 使缓冲的日志落盘后退出进程
+func fatalTextSync(w Writer, msg string, fields ...LogField) {
+	if shallLogTo(w, FatalLevel) {
+		w.Fatal(fmt.Sprintf("%s\n%s", msg, string(debug.Stack())), fields...)
+	}
+
+	_ = w.Close()
+	exit(1)
+}
+
 // infoTextSync 写入 Info 级别日志
-func infoTextSync(w Writer, msg string) {
-	if shallLog(InfoLevel) {
-		w.Info(msg)
+func infoTextSync(w Writer, msg string, fields ...LogField) {
+	if shallLogTo(w, InfoLevel) {
+		w.Info(msg, fields...)
 	}
 }
 
+// warnTextSync 写入 Warn 级别日志
+func warnTextSync(w Writer, msg string, fields ...LogField) {
+	if shallLogTo(w, WarnLevel) {
+		w.Warn(msg, fields...)
+	}
+}
+
+// loadConf 获取当前的日志配置
+func loadConf() *LogConf {
+	confLock.RLock()
+	defer confLock.RUnlock()
+	return conf
+}
+
+// storeConf 保存当前的日志配置
+func storeConf(c LogConf) {
+	confLock.Lock()
+	defer confLock.Unlock()
+	conf = &c
+}
+
+// flushWriter 刷新 w，w 不支持时直接返回
+func flushWriter(w Writer) error {
+	if f, ok := w.(flusher); ok {
+		return f.Flush()
+	}
+
+	return nil
+}
+
 // getWriter 获取 writer
 func getWriter() Writer {
 	w := writer.Load()
 	if w == nil {
-		w = writer.StoreIfNil(newConsoleWriter())
+		w = writer.StoreIfNil(newConsoleWriter(LogConf{}))
 	}
 	return w
 }
 
+// holdWriter 获取 writer，release 之前 Reload 和 Close 会等待，不会关闭该 writer
+func holdWriter() *heldWriter {
+	for {
+		if h := writer.hold(); h != nil {
+			return h
+		}
+
+		writer.StoreIfNil(newConsoleWriter(LogConf{}))
+	}
+}
+
 // SetWriter 设置日志 writer，用于自定义日志
 func SetWriter(w Writer) {
 	writer.Store(w)
@@ -182,65 +567,52 @@ func shallLog(level uint32) bool {
 	return atomic.LoadUint32(&logLevel) <= level
 }
 
-// setupLogLevel 设置日志级别
-func setupLogLevel(c LogConf) {
-	switch c.Level {
-	case levelInfo:
-		SetLevel(InfoLevel)
-	case levelError:
-		SetLevel(ErrorLevel)
-	}
-}
-
-func setupPath(c LogConf) {
-	if len(c.Path) == 0 {
-		c.Path = "logs"
+// shallLogTo 判断 w 是否可以记录该日志级别，w 有独立的日志级别时使用 w 的级别
+func shallLogTo(w Writer, level uint32) bool {
+	if lw, ok := w.(leveledWriter); ok {
+		return lw.shallLog(level)
 	}
-}
 
-func setupTimeFormat(c LogConf) {
-	if len(c.TimeFormat) > 0 {
-		timeFormat = c.TimeFormat
-	}
+	return shallLog(level)
 }
 
-func setupPlainEncodingSep(c LogConf) {
-	if len(c.PlainEncodingSep) > 0 {
-		plainEncodingSep = c.PlainEncodingSep
-	}
-}
-
-func setupWithColor(c LogConf) {
-	if c.WithColor {
-		withColor = c.WithColor
+// setupLogLevel 设置日志级别
+func setupLogLevel(c LogConf) {
+	if level, ok := parseLevel(c.Level); ok {
+		SetLevel(level)
 	}
 }
 
-func setupEncoding(c LogConf) {
-	switch c.Encoding {
-	case plainEncoding:
-		atomic.StoreUint32(&encoding, plainEncodingType)
+// parseLevel 解析日志级别字符串
+func parseLevel(level string) (uint32, bool) {
+	switch level {
+	case levelDebug:
+		return DebugLevel, true
+	case levelInfo:
+		return InfoLevel, true
+	case levelWarn:
+		return WarnLevel, true
+	case levelError:
+		return ErrorLevel, true
+	case levelFatal:
+		return FatalLevel, true
 	default:
-		atomic.StoreUint32(&encoding, jsonEncodingType)
+		return 0, false
 	}
 }
 
-func setupWriter(c LogConf) (err error) {
-	switch c.Mode {
-	case fileMode:
-		err = setupWithFiles(c)
-	default:
-		setupWithConsole()
+func setupPath(c LogConf) {
+	if len(c.Path) == 0 {
+		c.Path = "logs"
 	}
-	return
 }
 
-func setupWithConsole() {
-	SetWriter(newConsoleWriter())
+func setupEncoding(c LogConf) {
+	storeEncoderConf(newEncoderConf(c))
 }
 
-func setupWithFiles(c LogConf) error {
-	w, err := newFileWriter(c, "logx")
+func setupWriter(c LogConf) error {
+	w, err := newModeWriter(c)
 	if err != nil {
 		return err
 	}
@@ -2,15 +2,19 @@ package logx
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/stretchr/testify/assert"
 	"io"
 	"log"
+	"os"
+	"path"
 	"runtime"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
+	"time"
 )
 
 var (
@@ -27,16 +31,34 @@ type mockWriter struct {
 	builder strings.Builder
 }
 
-func (mw *mockWriter) Error(v interface{}) {
+func (mw *mockWriter) Debug(v interface{}, fields ...LogField) {
 	mw.lock.Lock()
 	defer mw.lock.Unlock()
-	output(&mw.builder, levelError, v)
+	output(&mw.builder, loadEncoderConf(), levelDebug, v, fields...)
 }
 
-func (mw *mockWriter) Info(v interface{}) {
+func (mw *mockWriter) Error(v interface{}, fields ...LogField) {
 	mw.lock.Lock()
 	defer mw.lock.Unlock()
-	output(&mw.builder, levelInfo, v)
+	output(&mw.builder, loadEncoderConf(), levelError, v, fields...)
+}
+
+func (mw *mockWriter) Fatal(v interface{}, fields ...LogField) {
+	mw.lock.Lock()
+	defer mw.lock.Unlock()
+	output(&mw.builder, loadEncoderConf(), levelFatal, v, fields...)
+}
+
+func (mw *mockWriter) Info(v interface{}, fields ...LogField) {
+	mw.lock.Lock()
+	defer mw.lock.Unlock()
+	output(&mw.builder, loadEncoderConf(), levelInfo, v, fields...)
+}
+
+func (mw *mockWriter) Warn(v interface{}, fields ...LogField) {
+	mw.lock.Lock()
+	defer mw.lock.Unlock()
+	output(&mw.builder, loadEncoderConf(), levelWarn, v, fields...)
 }
 
 func (mw *mockWriter) Close() error {
@@ -130,17 +152,235 @@ func TestStructedLogInfof(t *testing.T) {
 	})
 }
 
+func TestStructedLogDebug(t *testing.T) {
+	SetLevel(DebugLevel)
+	defer SetLevel(InfoLevel)
+
+	w := new(mockWriter)
+	old := writer.Swap(w)
+	defer writer.Store(old)
+
+	doTestStructedLog(t, levelDebug, w, func(v ...interface{}) {
+		Debug(v...)
+	})
+}
+
+func TestStructedLogDebugf(t *testing.T) {
+	SetLevel(DebugLevel)
+	defer SetLevel(InfoLevel)
+
+	w := new(mockWriter)
+	old := writer.Swap(w)
+	defer writer.Store(old)
+
+	doTestStructedLog(t, levelDebug, w, func(v ...interface{}) {
+		Debugf("%s", fmt.Sprint(v...))
+	})
+}
+
+func TestStructedLogWarn(t *testing.T) {
+	w := new(mockWriter)
+	old := writer.Swap(w)
+	defer writer.Store(old)
+
+	doTestStructedLog(t, levelWarn, w, func(v ...interface{}) {
+		Warn(v...)
+	})
+}
+
+func TestStructedLogWarnf(t *testing.T) {
+	w := new(mockWriter)
+	old := writer.Swap(w)
+	defer writer.Store(old)
+
+	doTestStructedLog(t, levelWarn, w, func(v ...interface{}) {
+		Warnf("%s", fmt.Sprint(v...))
+	})
+}
+
+func TestStructedLogFatal(t *testing.T) {
+	w := new(mockWriter)
+	old := writer.Swap(w)
+	defer writer.Store(old)
+
+	var code int
+	exit = func(c int) {
+		code = c
+	}
+	defer func() {
+		exit = os.Exit
+	}()
+
+	doTestStructedLog(t, levelFatal, w, func(v ...interface{}) {
+		Fatal(v...)
+	})
+	assert.Equal(t, 1, code)
+}
+
+func TestStructedLogFatalf(t *testing.T) {
+	w := new(mockWriter)
+	old := writer.Swap(w)
+	defer writer.Store(old)
+
+	var code int
+	exit = func(c int) {
+		code = c
+	}
+	defer func() {
+		exit = os.Exit
+	}()
+
+	doTestStructedLog(t, levelFatal, w, func(v ...interface{}) {
+		Fatalf("%s", fmt.Sprint(v...))
+	})
+	assert.Equal(t, 1, code)
+}
+
+func TestDebugDisabledByDefault(t *testing.T) {
+	w := new(mockWriter)
+	old := writer.Swap(w)
+	defer writer.Store(old)
+
+	Debug("hello there")
+	Debugf("hello %s", "there")
+	assert.Equal(t, 0, w.builder.Len())
+}
+
+func TestStructedLogInfow(t *testing.T) {
+	w := new(mockWriter)
+	old := writer.Swap(w)
+	defer writer.Store(old)
+
+	doTestStructedLog(t, levelInfo, w, func(v ...interface{}) {
+		Infow(fmt.Sprint(v...), String("foo", "bar"))
+	})
+}
+
+func TestStructedLogErrorw(t *testing.T) {
+	w := new(mockWriter)
+	old := writer.Swap(w)
+	defer writer.Store(old)
+
+	doTestStructedLog(t, levelError, w, func(v ...interface{}) {
+		Errorw(fmt.Sprint(v...), String("foo", "bar"))
+	})
+}
+
+func TestStructedLogWithFields(t *testing.T) {
+	w := new(mockWriter)
+	old := writer.Swap(w)
+	defer writer.Store(old)
+
+	now := time.Now()
+	Infow("hello there",
+		String("foo", "bar"),
+		Int("count", 3),
+		Duration("elapsed", time.Second),
+		Err(errors.New("boom")),
+		Any("nested", map[string]int{"a": 1}),
+		Any("at", now),
+		Any("timeout", time.Minute),
+		Any("cause", errors.New("bad")),
+	)
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(w.String()), &entry); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, levelInfo, entry[levelKey])
+	assert.Equal(t, "hello there", entry[contentKey])
+	assert.Equal(t, "bar", entry["foo"])
+	assert.Equal(t, float64(3), entry["count"])
+	assert.Equal(t, "1s", entry["elapsed"])
+	assert.Equal(t, "boom", entry[errorKey])
+	assert.Equal(t, map[string]interface{}{"a": float64(1)}, entry["nested"])
+	assert.Equal(t, now.Format(time.RFC3339Nano), entry["at"])
+	assert.Equal(t, "1m0s", entry["timeout"])
+	assert.Equal(t, "bad", entry["cause"])
+}
+
+func TestStructedLogWithFieldsPlainText(t *testing.T) {
+	w := new(mockWriter)
+	old := writer.Swap(w)
+	defer writer.Store(old)
+
+	oldEncoder := loadEncoderConf()
+	storeEncoderConf(newEncoderConf(LogConf{Encoding: plainEncoding}))
+	defer storeEncoderConf(oldEncoder)
+
+	now := time.Now()
+	Infow("hello there", String("foo", "bar"), Int("count", 3), Any("at", now))
+	assert.True(t, w.Contains("hello there"))
+	assert.True(t, w.Contains("foo=bar"))
+	assert.True(t, w.Contains("count=3"))
+	assert.True(t, w.Contains("at="+now.Format(time.RFC3339Nano)))
+	assert.False(t, w.Contains("m=+"))
+}
+
+func TestWith(t *testing.T) {
+	w := new(mockWriter)
+	old := writer.Swap(w)
+	defer writer.Store(old)
+
+	l := With(String("service", "api"))
+	child := l.With(String("request_id", "r1"))
+	child.Infow("hello there", Int("count", 1))
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(w.String()), &entry); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "api", entry["service"])
+	assert.Equal(t, "r1", entry["request_id"])
+	assert.Equal(t, float64(1), entry["count"])
+
+	w.Reset()
+	l.Info("hello there")
+	if err := json.Unmarshal([]byte(w.String()), &entry); err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, w.Contains("request_id"))
+	assert.Equal(t, "api", entry["service"])
+}
+
+func TestWithOverriddenField(t *testing.T) {
+	w := new(mockWriter)
+	old := writer.Swap(w)
+	defer writer.Store(old)
+	oldLevel := atomic.LoadUint32(&logLevel)
+	SetLevel(InfoLevel)
+	defer SetLevel(oldLevel)
+
+	With(String("k", "1")).Infow("hello there", String("k", "2"))
+	assert.Equal(t, 1, strings.Count(w.String(), `"k":`))
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(w.String()), &entry); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "2", entry["k"])
+}
+
+func TestWithSharesWriter(t *testing.T) {
+	w := new(mockWriter)
+	l := &logger{lw: w}
+	child := l.With(String("tenant", "t1"))
+
+	file, line := getFileLine()
+	child.Error("anything")
+	assert.True(t, w.Contains(fmt.Sprintf("%s:%d", file, line+1)))
+	assert.True(t, w.Contains(`"tenant":"t1"`))
+	assert.Equal(t, 0, len(l.fields))
+}
+
 func TestStructedLogInfoConsoleText(t *testing.T) {
 	w := new(mockWriter)
 	old := writer.Swap(w)
 	defer writer.Store(old)
 
 	doTestStructedLogConsole(t, w, func(v ...interface{}) {
-		old := atomic.LoadUint32(&encoding)
-		atomic.StoreUint32(&encoding, plainEncodingType)
-		defer func() {
-			atomic.StoreUint32(&encoding, old)
-		}()
+		old := loadEncoderConf()
+		storeEncoderConf(newEncoderConf(LogConf{Encoding: plainEncoding}))
+		defer storeEncoderConf(old)
 
 		Info(fmt.Sprint(v...))
 	})
@@ -148,6 +388,7 @@ func TestStructedLogInfoConsoleText(t *testing.T) {
 
 func TestSetLevel(t *testing.T) {
 	SetLevel(ErrorLevel)
+	defer SetLevel(InfoLevel)
 	const message = "hello there"
 	w := new(mockWriter)
 	old := writer.Swap(w)
@@ -155,6 +396,8 @@ func TestSetLevel(t *testing.T) {
 
 	Info(message)
 	assert.Equal(t, 0, w.builder.Len())
+	Warn(message)
+	assert.Equal(t, 0, w.builder.Len())
 }
 
 func TestSetLevelTwiceWithMode(t *testing.T) {
@@ -172,6 +415,173 @@ func TestSetLevelTwiceWithMode(t *testing.T) {
 	}
 }
 
+func TestNew(t *testing.T) {
+	oldLevel := atomic.LoadUint32(&logLevel)
+	SetLevel(InfoLevel)
+	defer SetLevel(oldLevel)
+
+	w := new(mockWriter)
+	old := writer.Swap(w)
+	defer writer.Store(old)
+
+	dir := t.TempDir()
+	l, err := New(LogConf{
+		Mode:             fileMode,
+		Encoding:         plainEncoding,
+		PlainEncodingSep: "|",
+		Path:             dir,
+		Level:            levelDebug,
+	})
+	assert.Nil(t, err)
+	l.Debugw("scoped", String("foo", "bar"))
+	assert.Nil(t, l.Close())
+
+	Debug("global debug")
+	Info("global info")
+
+	content, err := os.ReadFile(path.Join(dir, "logx.log"))
+	assert.Nil(t, err)
+	assert.True(t, strings.Contains(string(content), "|debug|scoped|foo=bar|"))
+	assert.False(t, w.Contains("global debug"))
+	var entry logEntry
+	assert.Nil(t, json.Unmarshal([]byte(w.String()), &entry))
+	assert.Equal(t, "global info", entry.Content)
+}
+
+func TestNewFileInUse(t *testing.T) {
+	dir := t.TempDir()
+	first, err := New(LogConf{Mode: fileMode, Path: dir})
+	assert.Nil(t, err)
+	defer first.Close()
+
+	_, err = New(LogConf{Mode: fileMode, Path: dir, Encoding: plainEncoding})
+	assert.Contains(t, err.Error(), "logx.log is already open")
+	_, err = New(LogConf{Mode: fileMode, Path: dir + "/", SplitByLevel: true})
+	assert.NotNil(t, err)
+
+	second, err := New(LogConf{Mode: fileMode, Path: dir, Filename: "audit", Encoding: plainEncoding})
+	assert.Nil(t, err)
+	second.Info("audit")
+	assert.Nil(t, second.Close())
+	content, err := os.ReadFile(path.Join(dir, "audit.log"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(content), "\tinfo\taudit")
+
+	// the files are free after closed
+	third, err := New(LogConf{Mode: fileMode, Path: dir, Filename: "audit"})
+	assert.Nil(t, err)
+	assert.Nil(t, third.Close())
+}
+
+func TestReload(t *testing.T) {
+	oldLevel := atomic.LoadUint32(&logLevel)
+	oldEncoder := loadEncoderConf()
+	old := writer.Load()
+	oldConf := loadConf()
+	defer func() {
+		SetLevel(oldLevel)
+		storeEncoderConf(oldEncoder)
+		writer.Store(old)
+		storeConf(*oldConf)
+	}()
+
+	first, second := t.TempDir(), t.TempDir()
+	assert.Nil(t, Reload(LogConf{Mode: fileMode, Path: first, Level: levelError}))
+	Info("dropped by level")
+	Error("first")
+
+	assert.Nil(t, Reload(LogConf{Mode: fileMode, Path: second, Encoding: plainEncoding, Level: levelInfo}))
+	Info("second")
+	assert.Nil(t, Close())
+
+	content, err := os.ReadFile(path.Join(first, "logx.log"))
+	assert.Nil(t, err)
+	assert.False(t, strings.Contains(string(content), "dropped by level"))
+	var entry logEntry
+	assert.Nil(t, json.Unmarshal(content, &entry))
+	assert.True(t, strings.HasPrefix(entry.Content.(string), "first"))
+
+	content, err = os.ReadFile(path.Join(second, "logx.log"))
+	assert.Nil(t, err)
+	assert.True(t, strings.Contains(string(content), "\tinfo\tsecond\t"))
+	assert.Equal(t, second, loadConf().Path)
+}
+
+func TestReloadConcurrently(t *testing.T) {
+	old := writer.Load()
+	oldConf := loadConf()
+	oldEncoder := loadEncoderConf()
+	defer func() {
+		writer.Store(old)
+		storeConf(*oldConf)
+		storeEncoderConf(oldEncoder)
+	}()
+
+	dir := t.TempDir()
+	assert.Nil(t, Reload(LogConf{Mode: fileMode, Path: dir, Level: "info"}))
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				Infow("hello", Int("j", j))
+			}
+		}()
+	}
+	for _, encoding := range []string{plainEncoding, jsonEncoding, plainEncoding} {
+		assert.Nil(t, Reload(LogConf{Mode: fileMode, Path: dir, Level: "info", Encoding: encoding}))
+	}
+	wg.Wait()
+	assert.Nil(t, Close())
+
+	// no entry is lost while the writers are replaced
+	content, err := os.ReadFile(path.Join(dir, "logx.log"))
+	assert.Nil(t, err)
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	assert.Equal(t, 5*100, len(lines))
+	for _, line := range lines {
+		assert.Contains(t, line, "hello")
+	}
+}
+
+func TestReloadWhileWriting(t *testing.T) {
+	old := writer.Load()
+	oldConf := loadConf()
+	oldEncoder := loadEncoderConf()
+	defer func() {
+		writer.Store(old)
+		storeConf(*oldConf)
+		storeEncoderConf(oldEncoder)
+	}()
+
+	first, second := t.TempDir(), t.TempDir()
+	assert.Nil(t, Reload(LogConf{Mode: fileMode, Path: first, Level: "info"}))
+	h := holdWriter()
+	reloaded := make(chan error, 1)
+	go func() {
+		reloaded <- Reload(LogConf{Mode: fileMode, Path: second, Level: "info"})
+	}()
+	for writer.Load() == h.writer {
+		time.Sleep(time.Millisecond)
+	}
+
+	// the old writer is replaced, but not closed until released
+	select {
+	case <-reloaded:
+		t.Fatal("the old writer is closed while held")
+	default:
+	}
+	h.writer.Info("in flight")
+	h.release()
+	assert.Nil(t, <-reloaded)
+	assert.Nil(t, Close())
+
+	content, err := os.ReadFile(path.Join(first, "logx.log"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(content), "in flight")
+}
+
 func TestSetWriter(t *testing.T) {
 	mocked := new(mockWriter)
 	SetWriter(mocked)
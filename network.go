@@ -0,0 +1,314 @@
+package logx
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	defaultNetworkDialTimeout  = 5 * time.Second
+	defaultNetworkWriteTimeout = 5 * time.Second
+	initialNetworkBackoff      = 100 * time.Millisecond
+	maxNetworkBackoff          = 30 * time.Second
+	spoolRetryInterval         = time.Second
+)
+
+var (
+	ErrNetworkWriterClosed = errors.New("error: network writer closed")
+
+	errNetworkBackoff = errors.New("error: waiting to reconnect")
+)
+
+type (
+	// networkLogger sends the encoded entries to a tcp or udp endpoint in its worker goroutine,
+	// or a syslog server if dialed by dialSyslog. It reconnects with exponential backoff, the entries
+	// are written to the spool if enabled, or the fallback, while the endpoint is unreachable.
+	networkLogger struct {
+		*asyncQueue
+		network string
+		address string
+		dial    dialFunc
+
+		// the fields below are only accessed by the worker
+		conn         networkConn
+		fallback     io.Writer
+		dialTimeout  time.Duration
+		writeTimeout time.Duration
+		minBackoff   time.Duration
+		maxBackoff   time.Duration
+		backoff      time.Duration
+		nextDial     time.Time
+		// spool is nil if spooling is disabled
+		spool         *spool
+		retryInterval time.Duration
+		// closeErr is set by the worker before it closes stopped
+		closeErr error
+	}
+
+	// networkConn is the connection to the endpoint, each Write sends one encoded entry.
+	networkConn interface {
+		io.WriteCloser
+		SetWriteDeadline(t time.Time) error
+	}
+
+	dialFunc func(network, address string, timeout time.Duration) (networkConn, error)
+)
+
+// NewNetworkWriter 创建将日志发送到 c.Address 的 Writer，c.Network 为 tcp 或 udp，默认 tcp。
+// 日志先写入长度为 c.QueueSize 的队列，队列满时按 c.Overflow 处理；连接断开后按指数退避重连，
+// 无法连接时日志写到标准错误。编码方式使用 c 中的配置，日志级别跟随全局配置，可以通过 SetWriter 使用
+func NewNetworkWriter(c LogConf) (Writer, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	l, err := newNetworkLogger(c)
+	if err != nil {
+		return nil, err
+	}
+	l.startWorker()
+
+	return &defaultWriter{
+		lw:   l,
+		conf: newEncoderConf(c),
+	}, nil
+}
+
+// newNetworkLogger returns a networkLogger with the settings in c, the worker is not started yet.
+func newNetworkLogger(c LogConf) (*networkLogger, error) {
+	network := c.Network
+	if len(network) == 0 {
+		network = tcpNetwork
+	}
+	if network != tcpNetwork && network != udpNetwork {
+		return nil, fmt.Errorf("logx: unsupported network %q, only tcp and udp are supported", network)
+	}
+	if len(c.Address) == 0 {
+		return nil, errors.New("logx: address of the network writer not set")
+	}
+
+	l := newConnLogger(network, c.Address, dialNetwork, c)
+	if err := l.openSpool(c); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// newConnLogger returns a networkLogger connecting to address by dial, without spooling.
+func newConnLogger(network, address string, dial dialFunc, c LogConf) *networkLogger {
+	return &networkLogger{
+		asyncQueue:    newAsyncQueue(c.QueueSize, c.Overflow),
+		network:       network,
+		address:       address,
+		dial:          dial,
+		fallback:      os.Stderr,
+		dialTimeout:   defaultNetworkDialTimeout,
+		writeTimeout:  defaultNetworkWriteTimeout,
+		minBackoff:    initialNetworkBackoff,
+		maxBackoff:    maxNetworkBackoff,
+		retryInterval: spoolRetryInterval,
+	}
+}
+
+// openSpool enables spooling if c.Spool is set, the spool is named after the endpoint of l.
+func (l *networkLogger) openSpool(c LogConf) error {
+	if !c.Spool {
+		return nil
+	}
+
+	maxSpoolSize := c.MaxSpoolSize
+	if maxSpoolSize <= 0 {
+		maxSpoolSize = defaultMaxSpoolSize
+	}
+
+	spool, err := newSpool(spoolPath(c, l.network, l.address), int64(maxSpoolSize)*megaBytes)
+	if err != nil {
+		return err
+	}
+
+	l.spool = spool
+	return nil
+}
+
+func dialNetwork(network, address string, timeout time.Duration) (networkConn, error) {
+	return net.DialTimeout(network, address, timeout)
+}
+
+// Close closes l after all the accepted entries are sent, or written to the spool or the fallback.
+func (l *networkLogger) Close() error {
+	l.shutdown()
+	<-l.stopped
+	return l.closeErr
+}
+
+func (l *networkLogger) Write(data []byte) (int, error) {
+	return l.push(data, ErrNetworkWriterClosed)
+}
+
+func (l *networkLogger) startWorker() {
+	go func() {
+		defer close(l.stopped)
+
+		ticker := time.NewTicker(l.retryInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case data := <-l.channel:
+				l.send(data)
+			case <-ticker.C:
+				l.replay()
+			case <-l.done:
+				for n := len(l.channel); n > 0; n-- {
+					l.send(<-l.channel)
+				}
+				l.disconnect()
+				if l.spool != nil {
+					l.closeErr = l.spool.close()
+				}
+				return
+			}
+		}
+	}()
+}
+
+// send writes data to the endpoint, or the spool or the fallback if it's unreachable.
+// Once the spool has entries, data is appended to the spool to keep the order.
+func (l *networkLogger) send(data []byte) {
+	if l.spool == nil {
+		if err := l.write(data); err != nil {
+			l.writeFallback(data)
+		}
+		return
+	}
+
+	if l.spool.empty() && l.write(data) == nil {
+		return
+	}
+
+	if err := l.spool.append(data); err != nil {
+		log.Printf("spool log entry failed: %s", err.Error())
+		l.writeFallback(data)
+	}
+	l.replay()
+}
+
+// replay sends the spooled entries in order, until the spool is empty or the endpoint fails.
+func (l *networkLogger) replay() {
+	if l.spool == nil {
+		return
+	}
+
+	for !l.spool.empty() {
+		data, err := l.spool.peek()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			log.Printf("read spool failed: %s", err.Error())
+			return
+		}
+
+		if err = l.write(data); err != nil {
+			return
+		}
+		l.spool.commit()
+	}
+}
+
+func (l *networkLogger) write(data []byte) error {
+	if l.conn == nil {
+		if err := l.connect(); err != nil {
+			return err
+		}
+	}
+
+	if l.writeTimeout > 0 {
+		_ = l.conn.SetWriteDeadline(time.Now().Add(l.writeTimeout))
+	}
+	if _, err := l.conn.Write(data); err != nil {
+		log.Printf("write log to %s://%s failed: %s", l.network, l.address, err.Error())
+		l.disconnect()
+		l.scheduleReconnect()
+		return err
+	}
+
+	return nil
+}
+
+// connect dials the endpoint if the backoff since the last failure expired.
+func (l *networkLogger) connect() error {
+	if time.Now().Before(l.nextDial) {
+		return errNetworkBackoff
+	}
+
+	conn, err := l.dial(l.network, l.address, l.dialTimeout)
+	if err != nil {
+		log.Printf("connect to %s://%s failed: %s", l.network, l.address, err.Error())
+		l.scheduleReconnect()
+		return err
+	}
+
+	l.conn = conn
+	l.backoff = 0
+	return nil
+}
+
+// scheduleReconnect doubles the backoff, from minBackoff up to maxBackoff.
+func (l *networkLogger) scheduleReconnect() {
+	if l.backoff == 0 {
+		l.backoff = l.minBackoff
+	} else {
+		l.backoff *= 2
+	}
+	if l.backoff > l.maxBackoff {
+		l.backoff = l.maxBackoff
+	}
+
+	l.nextDial = time.Now().Add(l.backoff)
+}
+
+func (l *networkLogger) disconnect() {
+	if l.conn == nil {
+		return
+	}
+
+	if err := l.conn.Close(); err != nil {
+		log.Println(err.Error())
+	}
+	l.conn = nil
+}
+
+// spoolPath returns the directory of the spool under c.Path, named after the endpoint,
+// so that the writers to different endpoints never share the spool.
+func spoolPath(c LogConf, network, address string) string {
+	dir := c.Path
+	if len(dir) == 0 {
+		dir = "logs"
+	}
+
+	name := strings.Map(func(r rune) rune {
+		switch r {
+		case ':', '/', '\\', '[', ']':
+			return '_'
+		default:
+			return r
+		}
+	}, network+"_"+address)
+
+	return path.Join(dir, spoolDir, name)
+}
+
+func (l *networkLogger) writeFallback(data []byte) {
+	if _, err := l.fallback.Write(data); err != nil {
+		log.Println(err.Error())
+	}
+}
@@ -0,0 +1,273 @@
+package logx
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNetworkWriterTCP(t *testing.T) {
+	listener, err := net.Listen(tcpNetwork, "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+
+	lines := make(chan string, 10)
+	go acceptLines(listener, lines)
+
+	w, err := NewNetworkWriter(LogConf{
+		Network:  tcpNetwork,
+		Address:  listener.Addr().String(),
+		Encoding: logfmtEncoding,
+	})
+	assert.Nil(t, err)
+
+	w.Info("first", String("foo", "bar"))
+	w.Error("second")
+	assert.Nil(t, w.Close())
+
+	assert.Contains(t, receiveLine(t, lines), `level=info msg=first`)
+	assert.Contains(t, receiveLine(t, lines), `level=error msg=second`)
+}
+
+func TestNetworkWriterUDP(t *testing.T) {
+	conn, err := net.ListenPacket(udpNetwork, "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	w, err := NewNetworkWriter(LogConf{Network: udpNetwork, Address: conn.LocalAddr().String()})
+	assert.Nil(t, err)
+	defer w.Close()
+
+	w.Warn("hello there")
+	buf := make([]byte, 4096)
+	assert.Nil(t, conn.SetReadDeadline(time.Now().Add(time.Second*5)))
+	n, _, err := conn.ReadFrom(buf)
+	assert.Nil(t, err)
+	assert.Contains(t, string(buf[:n]), `"level":"warn"`)
+	assert.True(t, strings.HasSuffix(string(buf[:n]), "}\n"))
+}
+
+func TestNetworkWriterFallback(t *testing.T) {
+	// reserve a port and release it, so that nothing is listening on it
+	listener, err := net.Listen(tcpNetwork, "127.0.0.1:0")
+	assert.Nil(t, err)
+	address := listener.Addr().String()
+	assert.Nil(t, listener.Close())
+
+	l, err := newNetworkLogger(LogConf{Address: address})
+	assert.Nil(t, err)
+	var fallback safeBuffer
+	l.fallback = &fallback
+	l.startWorker()
+
+	for i := 0; i < 3; i++ {
+		_, err = l.Write([]byte("hello\n"))
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, l.Close())
+	assert.Equal(t, "hello\nhello\nhello\n", fallback.String())
+	// the later entries are not sent while backing off
+	assert.Equal(t, l.minBackoff, l.backoff)
+
+	_, err = l.Write([]byte("closed\n"))
+	assert.Equal(t, ErrNetworkWriterClosed, err)
+}
+
+func TestNetworkWriterReconnect(t *testing.T) {
+	listener, err := net.Listen(tcpNetwork, "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+
+	// the first connection is closed right away, the entries are written to the fallback until reconnected
+	lines := make(chan string, 100)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		conn.Close()
+		acceptLines(listener, lines)
+	}()
+
+	l, err := newNetworkLogger(LogConf{Address: listener.Addr().String()})
+	assert.Nil(t, err)
+	var fallback safeBuffer
+	l.fallback = &fallback
+	l.minBackoff = time.Millisecond
+	l.startWorker()
+	defer l.Close()
+
+	deadline := time.After(time.Second * 5)
+	for {
+		_, err = l.Write([]byte("hello\n"))
+		assert.Nil(t, err)
+
+		select {
+		case line := <-lines:
+			assert.Equal(t, "hello", line)
+			return
+		case <-deadline:
+			t.Fatal("not reconnected")
+		case <-time.After(time.Millisecond * 10):
+		}
+	}
+}
+
+func TestNetworkWriterSpool(t *testing.T) {
+	// reserve a port and release it, the endpoint is started after some entries are spooled
+	listener, err := net.Listen(tcpNetwork, "127.0.0.1:0")
+	assert.Nil(t, err)
+	address := listener.Addr().String()
+	assert.Nil(t, listener.Close())
+
+	c := LogConf{Address: address, Path: t.TempDir(), Spool: true}
+	l, err := newNetworkLogger(c)
+	assert.Nil(t, err)
+	var fallback safeBuffer
+	l.fallback = &fallback
+	l.minBackoff = time.Millisecond
+	l.retryInterval = time.Millisecond * 10
+	l.startWorker()
+
+	for i := 0; i < 5; i++ {
+		_, err = l.Write([]byte(fmt.Sprintf("hello%d\n", i)))
+		assert.Nil(t, err)
+	}
+	assert.Eventually(t, func() bool {
+		entries, err := os.ReadDir(spoolPath(c, tcpNetwork, address))
+		return err == nil && len(entries) > 0
+	}, time.Second*5, time.Millisecond*10)
+
+	listener, err = net.Listen(tcpNetwork, address)
+	if err != nil {
+		t.Skip(err)
+	}
+	defer listener.Close()
+	lines := make(chan string, 10)
+	go acceptLines(listener, lines)
+
+	_, err = l.Write([]byte("hello5\n"))
+	assert.Nil(t, err)
+	for i := 0; i < 6; i++ {
+		assert.Equal(t, fmt.Sprintf("hello%d", i), receiveLine(t, lines))
+	}
+	assert.Nil(t, l.Close())
+	assert.Equal(t, "", fallback.String())
+	assert.True(t, l.spool.empty())
+}
+
+func TestNetworkWriterSpoolKeptOnClose(t *testing.T) {
+	listener, err := net.Listen(tcpNetwork, "127.0.0.1:0")
+	assert.Nil(t, err)
+	address := listener.Addr().String()
+	assert.Nil(t, listener.Close())
+
+	c := LogConf{Address: address, Path: t.TempDir(), Spool: true}
+	l, err := newNetworkLogger(c)
+	assert.Nil(t, err)
+	l.startWorker()
+	_, err = l.Write([]byte("hello\n"))
+	assert.Nil(t, err)
+	assert.Nil(t, l.Close())
+
+	// the next run replays the entries left by the previous one
+	s, err := newSpool(spoolPath(c, tcpNetwork, address), megaBytes)
+	assert.Nil(t, err)
+	defer s.close()
+	data, err := s.peek()
+	assert.Nil(t, err)
+	assert.Equal(t, "hello\n", string(data))
+}
+
+func TestNetworkWriterCloseError(t *testing.T) {
+	c := LogConf{Address: "127.0.0.1:514", Path: t.TempDir(), Spool: true}
+	l, err := newNetworkLogger(c)
+	assert.Nil(t, err)
+	// the segment is closed already, so that closing the spool fails
+	fp, err := os.Create(path.Join(t.TempDir(), "closed.seg"))
+	assert.Nil(t, err)
+	assert.Nil(t, fp.Close())
+	l.spool.tail = fp
+	l.startWorker()
+
+	assert.True(t, errors.Is(Tee(&defaultWriter{lw: l}).Close(), os.ErrClosed))
+	assert.True(t, errors.Is(l.Close(), os.ErrClosed))
+}
+
+func TestSpoolPath(t *testing.T) {
+	assert.Equal(t, "logs/spool/tcp_127.0.0.1_514", spoolPath(LogConf{}, tcpNetwork, "127.0.0.1:514"))
+	assert.Equal(t, "/var/log/spool/udp____1__514", spoolPath(LogConf{Path: "/var/log"}, udpNetwork, "[::1]:514"))
+}
+
+func TestNetworkLoggerBackoff(t *testing.T) {
+	l := &networkLogger{
+		minBackoff: time.Second,
+		maxBackoff: time.Second * 5,
+	}
+
+	for _, expect := range []time.Duration{time.Second, time.Second * 2, time.Second * 4, time.Second * 5, time.Second * 5} {
+		l.scheduleReconnect()
+		assert.Equal(t, expect, l.backoff)
+	}
+	assert.Equal(t, errNetworkBackoff, l.connect())
+}
+
+func TestNetworkWriterInvalidConf(t *testing.T) {
+	_, err := NewNetworkWriter(LogConf{Network: udpNetwork})
+	assert.NotNil(t, err)
+	_, err = NewNetworkWriter(LogConf{Network: unixNetwork, Address: "/dev/log"})
+	assert.NotNil(t, err)
+	_, err = NewNetworkWriter(LogConf{Address: "127.0.0.1:1", Overflow: "drop"})
+	assert.IsType(t, &ConfError{}, err)
+}
+
+func acceptLines(listener net.Listener, lines chan<- string) {
+	conn, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		lines <- scanner.Text()
+	}
+}
+
+func receiveLine(t *testing.T, lines <-chan string) string {
+	select {
+	case line := <-lines:
+		return line
+	case <-time.After(time.Second * 5):
+		t.Fatal("no line received")
+		return ""
+	}
+}
+
+// safeBuffer is a bytes.Buffer safe for concurrent use.
+type safeBuffer struct {
+	buf  bytes.Buffer
+	lock sync.Mutex
+}
+
+func (b *safeBuffer) Write(p []byte) (int, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *safeBuffer) String() string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buf.String()
+}
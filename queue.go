@@ -0,0 +1,97 @@
+package logx
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+)
+
+// asyncQueue queues the entries for the worker goroutine of a logger, like DefaultLogger,
+// the overflow policy applies if it's full. The worker consumes channel until done is closed,
+// then drains it and closes stopped.
+type asyncQueue struct {
+	// dropped counts the entries discarded by the overflow policy, accessed atomically,
+	// kept as the first field to be 64-bit aligned on 32-bit platforms
+	dropped  uint64
+	overflow string
+	channel  chan []byte
+	done     chan struct{}
+	stopped  chan struct{}
+	// lock guards closed, so that no entry is accepted after shutdown started
+	lock      sync.RWMutex
+	closed    bool
+	closeOnce sync.Once
+}
+
+func newAsyncQueue(size int, overflow string) *asyncQueue {
+	if size <= 0 {
+		size = defaultQueueSize
+	}
+
+	return &asyncQueue{
+		overflow: overflow,
+		channel:  make(chan []byte, size),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+}
+
+// Dropped returns the number of entries discarded because the queue was full.
+func (q *asyncQueue) Dropped() uint64 {
+	return atomic.LoadUint64(&q.dropped)
+}
+
+// push queues a copy of data, since the caller may reuse it after push returns.
+// errClosed is returned if the queue is shut down, and data is printed by the standard logger.
+func (q *asyncQueue) push(data []byte, errClosed error) (int, error) {
+	q.lock.RLock()
+	defer q.lock.RUnlock()
+
+	if q.closed {
+		log.Println(string(data))
+		return 0, errClosed
+	}
+
+	q.enqueue(append([]byte(nil), data...))
+	return len(data), nil
+}
+
+// shutdown stops accepting entries and tells the worker to stop, it's safe to call multiple times.
+func (q *asyncQueue) shutdown() {
+	q.closeOnce.Do(func() {
+		q.lock.Lock()
+		q.closed = true
+		q.lock.Unlock()
+
+		close(q.done)
+	})
+}
+
+// enqueue puts data into channel, applying the overflow policy if channel is full.
+func (q *asyncQueue) enqueue(data []byte) {
+	switch q.overflow {
+	case dropNewestOverflow:
+		select {
+		case q.channel <- data:
+		default:
+			atomic.AddUint64(&q.dropped, 1)
+		}
+	case dropOldestOverflow:
+		for {
+			select {
+			case q.channel <- data:
+				return
+			default:
+			}
+
+			select {
+			case <-q.channel:
+				atomic.AddUint64(&q.dropped, 1)
+			default:
+			}
+		}
+	default:
+		// the worker keeps consuming until shutdown, which waits for the read lock to be released
+		q.channel <- data
+	}
+}
@@ -0,0 +1,78 @@
+package logx
+
+import (
+	"log"
+	"path"
+	"sync"
+)
+
+var (
+	// fileLoggers are the open DefaultLoggers, reopened by Reopen
+	fileLoggers     = make(map[*DefaultLogger]struct{})
+	fileLoggersLock sync.Mutex
+	reopenOnce      sync.Once
+)
+
+// Reopen 重新打开所有日志文件，用于配合 logrotate 等外部工具切分日志：文件被重命名后，
+// 之后的日志写入按原文件名新建的文件，队列中尚未写入的日志不会丢失
+func Reopen() error {
+	var errs writerErrors
+	for _, l := range openFileLoggers() {
+		if err := l.Reopen(); err != nil && err != ErrLogFileClosed {
+			errs = append(errs, err)
+		}
+	}
+
+	return errs.err()
+}
+
+// ReopenOnSignal 在收到 SIGHUP 时调用 Reopen，多次调用只生效一次，Windows 下不做处理
+func ReopenOnSignal() {
+	reopenOnce.Do(func() {
+		notifyReopen(func() {
+			if err := Reopen(); err != nil {
+				log.Printf("reopen log files failed: %s", err.Error())
+			}
+		})
+	})
+}
+
+func registerFileLogger(l *DefaultLogger) {
+	fileLoggersLock.Lock()
+	fileLoggers[l] = struct{}{}
+	fileLoggersLock.Unlock()
+}
+
+func unregisterFileLogger(l *DefaultLogger) {
+	fileLoggersLock.Lock()
+	delete(fileLoggers, l)
+	fileLoggersLock.Unlock()
+}
+
+// openedFile returns the first of files that is open by a DefaultLogger.
+func openedFile(files []string) (string, bool) {
+	fileLoggersLock.Lock()
+	defer fileLoggersLock.Unlock()
+
+	for l := range fileLoggers {
+		for _, file := range files {
+			if path.Clean(l.filename) == path.Clean(file) {
+				return file, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+func openFileLoggers() []*DefaultLogger {
+	fileLoggersLock.Lock()
+	defer fileLoggersLock.Unlock()
+
+	loggers := make([]*DefaultLogger, 0, len(fileLoggers))
+	for l := range fileLoggers {
+		loggers = append(loggers, l)
+	}
+
+	return loggers
+}
@@ -0,0 +1,103 @@
+package logx
+
+import (
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultLoggerReopen(t *testing.T) {
+	filename := path.Join(t.TempDir(), "logx.log")
+	l, err := newLogger(filename, LogConf{})
+	assert.Nil(t, err)
+
+	_, err = l.Write([]byte("before\n"))
+	assert.Nil(t, err)
+	assert.Nil(t, l.Flush())
+	assert.Nil(t, os.Rename(filename, filename+".1"))
+
+	_, err = l.Write([]byte("moved\n"))
+	assert.Nil(t, err)
+	assert.Nil(t, l.Reopen())
+	_, err = l.Write([]byte("after\n"))
+	assert.Nil(t, err)
+	assert.Nil(t, l.Close())
+	assert.Equal(t, ErrLogFileClosed, l.Reopen())
+
+	content, err := os.ReadFile(filename + ".1")
+	assert.Nil(t, err)
+	assert.Equal(t, "before\nmoved\n", string(content))
+	content, err = os.ReadFile(filename)
+	assert.Nil(t, err)
+	assert.Equal(t, "after\n", string(content))
+}
+
+func TestReopen(t *testing.T) {
+	dir := t.TempDir()
+	first, err := newLogger(path.Join(dir, "first.log"), LogConf{})
+	assert.Nil(t, err)
+	second, err := newLogger(path.Join(dir, "second.log"), LogConf{})
+	assert.Nil(t, err)
+	assert.Nil(t, second.Close())
+	assert.NotContains(t, openFileLoggers(), second)
+
+	assert.Nil(t, os.Remove(path.Join(dir, "first.log")))
+	assert.Nil(t, Reopen())
+	_, err = first.Write([]byte("foo\n"))
+	assert.Nil(t, err)
+	assert.Nil(t, first.Close())
+
+	content, err := os.ReadFile(path.Join(dir, "first.log"))
+	assert.Nil(t, err)
+	assert.Equal(t, "foo\n", string(content))
+	_, err = os.Stat(path.Join(dir, "second.log"))
+	assert.Nil(t, err)
+}
+
+func TestDefaultLoggerRecreateRemovedFile(t *testing.T) {
+	oldInterval := fileCheckInterval
+	fileCheckInterval = 10 * time.Millisecond
+	defer func() {
+		fileCheckInterval = oldInterval
+	}()
+
+	dir := path.Join(t.TempDir(), "logs")
+	filename := path.Join(dir, "logx.log")
+	l, err := newLogger(filename, LogConf{Encoding: logfmtEncoding})
+	assert.Nil(t, err)
+	assert.Nil(t, os.RemoveAll(dir))
+
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat(filename)
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	_, err = l.Write([]byte("foo\n"))
+	assert.Nil(t, err)
+	assert.Nil(t, l.Close())
+
+	content, err := os.ReadFile(filename)
+	assert.Nil(t, err)
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	assert.Equal(t, 2, len(lines))
+	assert.Contains(t, lines[0], "level=warn")
+	assert.Contains(t, lines[0], "was removed or moved, recreated")
+	assert.Equal(t, "foo", lines[1])
+}
+
+func TestDefaultLoggerFileMoved(t *testing.T) {
+	filename := path.Join(t.TempDir(), "logx.log")
+	// no worker is started, fileMoved is only called by the worker
+	l := &DefaultLogger{filename: filename}
+	assert.Nil(t, l.init())
+	defer l.closeFile()
+
+	assert.False(t, l.fileMoved())
+	assert.Nil(t, os.Rename(filename, filename+".1"))
+	assert.True(t, l.fileMoved())
+	assert.Nil(t, os.WriteFile(filename, nil, defaultFileMode))
+	assert.True(t, l.fileMoved())
+}
@@ -0,0 +1,197 @@
+package logx
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	noneRotation   = "none"
+	dailyRotation  = "daily"
+	hourlyRotation = "hourly"
+
+	dailyLayout  = "2006-01-02"
+	hourlyLayout = "2006-01-02T15"
+	sizeLayout   = "2006-01-02T15-04-05"
+
+	backupDelimiter = "."
+	gzipExt         = ".gz"
+	megaBytes       = 1 << 20
+	hoursPerDay     = 24
+)
+
+// currentTime is replaceable in tests.
+var currentTime = time.Now
+
+type (
+	// A rotateRule decides when the log file should be rotated and how the backup is named.
+	rotateRule interface {
+		BackupFileName() string
+		MarkRotated()
+		OutdatedFiles() []string
+		ShallRotate(size int64) bool
+	}
+
+	// periodRotateRule rotates the log file when the day or hour changes,
+	// or when the file would grow beyond maxSize bytes.
+	periodRotateRule struct {
+		filename    string
+		layout      string
+		maxSize     int64
+		keepDays    int
+		maxBackups  int
+		rotatedTime string
+	}
+)
+
+// newRotateRule returns the rotateRule described by c, or nil if rotation is disabled.
+func newRotateRule(filename string, c LogConf) rotateRule {
+	var layout string
+	switch c.Rotation {
+	case dailyRotation:
+		layout = dailyLayout
+	case hourlyRotation:
+		layout = hourlyLayout
+	}
+
+	if len(layout) == 0 && c.MaxSize <= 0 {
+		return nil
+	}
+
+	rule := &periodRotateRule{
+		filename:   filename,
+		layout:     layout,
+		maxSize:    int64(c.MaxSize) * megaBytes,
+		keepDays:   c.KeepDays,
+		maxBackups: c.MaxBackups,
+	}
+	rule.MarkRotated()
+
+	return rule
+}
+
+// BackupFileName returns the name the current file is renamed to, a sequence suffix is
+// appended if a backup with the same timestamp already exists.
+func (r *periodRotateRule) BackupFileName() string {
+	var backup string
+	if len(r.layout) > 0 {
+		backup = r.filename + backupDelimiter + r.rotatedTime
+	} else {
+		backup = r.filename + backupDelimiter + currentTime().Format(sizeLayout)
+	}
+
+	if !backupExists(backup) {
+		return backup
+	}
+
+	for seq := 1; ; seq++ {
+		name := fmt.Sprintf("%s%s%d", backup, backupDelimiter, seq)
+		if !backupExists(name) {
+			return name
+		}
+	}
+}
+
+// MarkRotated marks the rule's rotated time to be the current period.
+func (r *periodRotateRule) MarkRotated() {
+	if len(r.layout) > 0 {
+		r.rotatedTime = currentTime().Format(r.layout)
+	}
+}
+
+// OutdatedFiles returns the backups older than keepDays, and the ones beyond the newest maxBackups.
+func (r *periodRotateRule) OutdatedFiles() []string {
+	if r.keepDays <= 0 && r.maxBackups <= 0 {
+		return nil
+	}
+
+	backups, err := filepath.Glob(r.filename + backupDelimiter + "*")
+	if err != nil {
+		return nil
+	}
+
+	type backupFile struct {
+		name    string
+		modTime time.Time
+	}
+	files := make([]backupFile, 0, len(backups))
+	for _, name := range backups {
+		info, err := os.Stat(name)
+		if err != nil {
+			continue
+		}
+		files = append(files, backupFile{name: name, modTime: info.ModTime()})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.After(files[j].modTime)
+	})
+
+	var outdated []string
+	boundary := currentTime().Add(-time.Hour * time.Duration(hoursPerDay*r.keepDays))
+	for i, file := range files {
+		if (r.maxBackups > 0 && i >= r.maxBackups) || (r.keepDays > 0 && file.modTime.Before(boundary)) {
+			outdated = append(outdated, file.name)
+		}
+	}
+
+	return outdated
+}
+
+// ShallRotate checks if the file should be rotated before it grows to size.
+func (r *periodRotateRule) ShallRotate(size int64) bool {
+	if len(r.layout) > 0 && currentTime().Format(r.layout) != r.rotatedTime {
+		return true
+	}
+
+	return r.maxSize > 0 && size > r.maxSize
+}
+
+func backupExists(name string) bool {
+	for _, file := range []string{name, name + gzipExt} {
+		if _, err := os.Stat(file); err == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// compressFile gzips file into file.gz and removes file, the partial .gz is removed on failure.
+func compressFile(file string) (err error) {
+	in, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	gzFile := file + gzipExt
+	out, err := os.OpenFile(gzFile, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, defaultFileMode)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(gzFile)
+		}
+	}()
+
+	w := gzip.NewWriter(out)
+	if _, err = io.Copy(w, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err = w.Close(); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err = out.Close(); err != nil {
+		return err
+	}
+
+	return os.Remove(file)
+}
@@ -0,0 +1,171 @@
+package logx
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+const (
+	ecsSchema     = "ecs"
+	gcpSchema     = "gcp"
+	datadogSchema = "datadog"
+)
+
+// jsonSchema decides the keys of the json entries and how levels and fields are mapped.
+type jsonSchema struct {
+	timestampKey string
+	levelKey     string
+	callerKey    string
+	contentKey   string
+	// callerLineKey writes the line of the caller as an integer with this key, and the file
+	// with callerKey, like ECS requires
+	callerLineKey string
+	// levels maps the logx levels to the ones of the schema, unmapped levels are kept as is
+	levels map[string]string
+	// fields maps the field keys to the ones of the schema, unmapped keys are kept as is
+	fields map[string]string
+	// sourceLocation writes the caller as an object of file and line, like GCP requires
+	sourceLocation bool
+}
+
+var (
+	defaultSchema = jsonSchema{
+		timestampKey: timestampKey,
+		levelKey:     levelKey,
+		callerKey:    callerKey,
+		contentKey:   contentKey,
+	}
+
+	// schemas are the built-in presets selected by LogConf.Schema
+	schemas = map[string]jsonSchema{
+		// Elastic Common Schema, https://www.elastic.co/guide/en/ecs/current/index.html
+		ecsSchema: {
+			timestampKey:  "@timestamp",
+			levelKey:      "log.level",
+			callerKey:     "log.origin.file.name",
+			callerLineKey: "log.origin.file.line",
+			contentKey:    "message",
+			fields: map[string]string{
+				errorKey: "error.message",
+				traceKey: "trace.id",
+				spanKey:  "span.id",
+			},
+		},
+		// Google Cloud Logging, https://cloud.google.com/logging/docs/structured-logging
+		gcpSchema: {
+			timestampKey: "time",
+			levelKey:     "severity",
+			callerKey:    "logging.googleapis.com/sourceLocation",
+			contentKey:   "message",
+			levels: map[string]string{
+				levelDebug: "DEBUG",
+				levelInfo:  "INFO",
+				levelWarn:  "WARNING",
+				levelError: "ERROR",
+				levelFatal: "CRITICAL",
+			},
+			fields: map[string]string{
+				traceKey: "logging.googleapis.com/trace",
+				spanKey:  "logging.googleapis.com/spanId",
+			},
+			sourceLocation: true,
+		},
+		// Datadog, https://docs.datadoghq.com/logs/log_configuration/attributes_naming_convention
+		datadogSchema: {
+			timestampKey: "timestamp",
+			levelKey:     "status",
+			callerKey:    "logger.caller",
+			contentKey:   "message",
+			levels: map[string]string{
+				levelFatal: "critical",
+			},
+			fields: map[string]string{
+				errorKey: "error.message",
+				traceKey: "dd.trace_id",
+				spanKey:  "dd.span_id",
+			},
+		},
+	}
+)
+
+// newJsonSchema returns the schema preset in c.Schema, with the keys renamed in c.
+func newJsonSchema(c LogConf) jsonSchema {
+	schema := defaultSchema
+	if preset, ok := schemas[c.Schema]; ok {
+		schema = preset
+	}
+
+	if len(c.TimestampKey) > 0 {
+		schema.timestampKey = c.TimestampKey
+	}
+	if len(c.LevelKey) > 0 {
+		schema.levelKey = c.LevelKey
+	}
+	if len(c.CallerKey) > 0 {
+		schema.callerKey = c.CallerKey
+	}
+	if len(c.ContentKey) > 0 {
+		schema.contentKey = c.ContentKey
+	}
+
+	return schema
+}
+
+func (s *jsonSchema) level(level string) string {
+	if mapped, ok := s.levels[level]; ok {
+		return mapped
+	}
+
+	return level
+}
+
+func (s *jsonSchema) field(key string) string {
+	if mapped, ok := s.fields[key]; ok {
+		return mapped
+	}
+
+	return key
+}
+
+// isReservedKey checks if key is written by the encoder itself, such fields are skipped
+// to keep the keys unique.
+func (s *jsonSchema) isReservedKey(key string) bool {
+	return key == s.timestampKey || key == s.levelKey || key == s.callerKey || key == s.contentKey ||
+		len(s.callerLineKey) > 0 && key == s.callerLineKey
+}
+
+// writeCaller writes caller as a string, or an object of file and line if sourceLocation is set,
+// or the file followed by the line with callerLineKey if set.
+func (s *jsonSchema) writeCaller(buf *bytes.Buffer, caller string) {
+	switch {
+	case s.sourceLocation:
+		file, line := splitCaller(caller)
+		buf.WriteString(`{"file":`)
+		writeJsonString(buf, file)
+		buf.WriteString(`,"line":`)
+		// line is a string in GCP's LogEntrySourceLocation
+		writeJsonString(buf, line)
+		buf.WriteByte('}')
+	case len(s.callerLineKey) > 0:
+		file, line := splitCaller(caller)
+		writeJsonString(buf, file)
+		if n, err := strconv.Atoi(line); err == nil {
+			buf.WriteByte(',')
+			writeJsonKey(buf, s.callerLineKey)
+			var scratch [20]byte
+			buf.Write(strconv.AppendInt(scratch[:0], int64(n), 10))
+		}
+	default:
+		writeJsonString(buf, caller)
+	}
+}
+
+// splitCaller splits caller like logx/schema.go:12 into the file and the line.
+func splitCaller(caller string) (string, string) {
+	if idx := strings.LastIndexByte(caller, ':'); idx >= 0 {
+		return caller[:idx], caller[idx+1:]
+	}
+
+	return caller, ""
+}
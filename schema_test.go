@@ -0,0 +1,107 @@
+package logx
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJsonSchemaPresets(t *testing.T) {
+	now := time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC)
+	entry := Entry{
+		Time:    now,
+		Level:   levelFatal,
+		Caller:  "logx/schema_test.go:12",
+		Content: "hello there",
+		Fields: []LogField{
+			Err(errors.New("boom")),
+			String(traceKey, "t1"),
+			String(spanKey, "s1"),
+		},
+	}
+
+	tests := []struct {
+		schema string
+		expect string
+	}{
+		{
+			schema: "",
+			expect: `{"@timestamp":"2026-10-16T08:00:00Z","level":"fatal","caller":"logx/schema_test.go:12",` +
+				`"content":"hello there","error":"boom","trace_id":"t1","span_id":"s1"}`,
+		},
+		{
+			schema: ecsSchema,
+			expect: `{"@timestamp":"2026-10-16T08:00:00Z","log.level":"fatal","log.origin.file.name":"logx/schema_test.go",` +
+				`"log.origin.file.line":12,"message":"hello there","error.message":"boom","trace.id":"t1","span.id":"s1"}`,
+		},
+		{
+			schema: gcpSchema,
+			expect: `{"time":"2026-10-16T08:00:00Z","severity":"CRITICAL",` +
+				`"logging.googleapis.com/sourceLocation":{"file":"logx/schema_test.go","line":"12"},` +
+				`"message":"hello there","error":"boom","logging.googleapis.com/trace":"t1",` +
+				`"logging.googleapis.com/spanId":"s1"}`,
+		},
+		{
+			schema: datadogSchema,
+			expect: `{"timestamp":"2026-10-16T08:00:00Z","status":"critical","logger.caller":"logx/schema_test.go:12",` +
+				`"message":"hello there","error.message":"boom","dd.trace_id":"t1","dd.span_id":"s1"}`,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.schema, func(t *testing.T) {
+			e := encoders[jsonEncoding](LogConf{Schema: test.schema, TimeFormat: time.RFC3339})
+			var buf bytes.Buffer
+			assert.Nil(t, e.Encode(&buf, entry))
+			assert.Equal(t, test.expect, buf.String())
+		})
+	}
+}
+
+func TestJsonSchemaRenameKeys(t *testing.T) {
+	e := encoders[jsonEncoding](LogConf{
+		Schema:       ecsSchema,
+		TimestampKey: "ts",
+		LevelKey:     "lvl",
+		CallerKey:    "src",
+		ContentKey:   "msg",
+		TimeFormat:   time.RFC3339,
+	})
+
+	var buf bytes.Buffer
+	assert.Nil(t, e.Encode(&buf, Entry{
+		Time:    time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC),
+		Level:   levelInfo,
+		Caller:  "logx/schema_test.go:1",
+		Content: "hello there",
+		Fields:  []LogField{String("msg", "ignored"), Err(errors.New("boom"))},
+	}))
+	assert.Equal(t, `{"ts":"2026-10-16T08:00:00Z","lvl":"info","src":"logx/schema_test.go",`+
+		`"log.origin.file.line":1,"msg":"hello there","error.message":"boom"}`, buf.String())
+}
+
+func TestJsonSchemaCallerLine(t *testing.T) {
+	schema := newJsonSchema(LogConf{Schema: ecsSchema})
+	var buf bytes.Buffer
+	schema.writeCaller(&buf, "")
+	assert.Equal(t, `""`, buf.String())
+	assert.True(t, schema.isReservedKey("log.origin.file.line"))
+	assert.False(t, defaultSchema.isReservedKey(""))
+}
+
+func TestJsonSchemaSourceLocationWithoutLine(t *testing.T) {
+	schema := newJsonSchema(LogConf{Schema: gcpSchema})
+	var buf bytes.Buffer
+	schema.writeCaller(&buf, "")
+	assert.Equal(t, `{"file":"","line":""}`, buf.String())
+}
+
+func TestValidateSchema(t *testing.T) {
+	assert.Nil(t, LogConf{Schema: gcpSchema}.Validate())
+	assert.EqualError(t, LogConf{Schema: "splunk"}.Validate(),
+		`logx: invalid LogConf: Schema: value "splunk" is not in options [ecs,gcp,datadog]`)
+}
@@ -0,0 +1,7 @@
+//go:build windows
+// +build windows
+
+package logx
+
+func notifyReopen(func()) {
+}
@@ -0,0 +1,22 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package logx
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// notifyReopen calls reopen on every SIGHUP.
+func notifyReopen(reopen func()) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+
+	go func() {
+		for range signals {
+			reopen()
+		}
+	}()
+}
@@ -0,0 +1,31 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package logx
+
+import (
+	"os"
+	"path"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReopenOnSignal(t *testing.T) {
+	filename := path.Join(t.TempDir(), "logx.log")
+	l, err := newLogger(filename, LogConf{})
+	assert.Nil(t, err)
+	defer l.Close()
+
+	ReopenOnSignal()
+	ReopenOnSignal()
+	assert.Nil(t, os.Remove(filename))
+	assert.Nil(t, syscall.Kill(os.Getpid(), syscall.SIGHUP))
+
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat(filename)
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,324 @@
+package logx
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/git-zjx/logx/fs"
+)
+
+const (
+	spoolDir            = "spool"
+	spoolExt            = ".seg"
+	spoolHeaderSize     = 4
+	defaultMaxSpoolSize = 100
+	spoolSegmentSize    = 4 * megaBytes
+)
+
+var errSpoolEntryTooLarge = errors.New("error: log entry larger than the spool")
+
+type (
+	// spool keeps the entries on disk while the remote is unreachable, in segment files named
+	// by increasing sequence numbers, each entry is prefixed by its length in 4 bytes big endian.
+	// It's not safe for concurrent use, only the worker of networkLogger accesses it.
+	spool struct {
+		dir         string
+		maxSize     int64
+		segmentSize int64
+		size        int64
+		nextSeq     uint64
+		// segments are ordered from the oldest to the newest
+		segments []*spoolSegment
+		// tail is the file of the last segment opened for appending, nil if no segment is open
+		tail spoolFile
+		// head is the reader of the first segment, nil if not replaying
+		head    *os.File
+		reader  *bufio.Reader
+		peeked  []byte
+		hasPeek bool
+		header  [spoolHeaderSize]byte
+	}
+
+	// spoolFile is the file of the segment being appended.
+	spoolFile interface {
+		io.WriteCloser
+		Truncate(size int64) error
+	}
+
+	spoolSegment struct {
+		filename string
+		size     int64
+	}
+)
+
+// newSpool opens the spool in dir, the segments left by the previous runs are replayed first.
+func newSpool(dir string, maxSize int64) (*spool, error) {
+	if err := os.MkdirAll(dir, defaultDirMode); err != nil {
+		return nil, err
+	}
+
+	s := &spool{
+		dir:         dir,
+		maxSize:     maxSize,
+		segmentSize: spoolSegmentSize,
+		nextSeq:     1,
+	}
+	if s.segmentSize > maxSize {
+		s.segmentSize = maxSize
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var seqs []uint64
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, spoolExt) {
+			continue
+		}
+
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, spoolExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		seqs = append(seqs, seq)
+	}
+	sort.Slice(seqs, func(i, j int) bool {
+		return seqs[i] < seqs[j]
+	})
+
+	for _, seq := range seqs {
+		filename := s.segmentName(seq)
+		info, err := os.Stat(filename)
+		if err != nil {
+			return nil, err
+		}
+
+		s.segments = append(s.segments, &spoolSegment{
+			filename: filename,
+			size:     info.Size(),
+		})
+		s.size += info.Size()
+		s.nextSeq = seq + 1
+	}
+
+	return s, nil
+}
+
+// empty checks if there are entries not replayed yet.
+func (s *spool) empty() bool {
+	return len(s.segments) == 0
+}
+
+// append adds data to the newest segment, the oldest segments are dropped if the spool is full.
+// A record is never left partially written, so that the later records can be replayed.
+func (s *spool) append(data []byte) error {
+	recordSize := int64(spoolHeaderSize + len(data))
+	// segmentSize is never larger than maxSize
+	if recordSize > s.segmentSize {
+		return errSpoolEntryTooLarge
+	}
+
+	for s.size+recordSize > s.maxSize && len(s.segments) > 0 {
+		s.dropOldest()
+	}
+
+	if s.tail == nil || s.segments[len(s.segments)-1].size+recordSize > s.segmentSize {
+		if err := s.openSegment(); err != nil {
+			return err
+		}
+	}
+
+	binary.BigEndian.PutUint32(s.header[:], uint32(len(data)))
+	segment := s.segments[len(s.segments)-1]
+	n, err := s.tail.Write(s.header[:])
+	if err == nil {
+		var m int
+		m, err = s.tail.Write(data)
+		n += m
+	}
+	if err != nil {
+		s.discardPartial(segment, int64(n))
+		return err
+	}
+
+	segment.size += int64(n)
+	s.size += int64(n)
+
+	return nil
+}
+
+// discardPartial removes the n bytes of a record failed to be appended to segment. If they can't be
+// truncated, the segment is closed, the partial record is skipped on replay as the last one in it.
+func (s *spool) discardPartial(segment *spoolSegment, n int64) {
+	if n == 0 {
+		return
+	}
+
+	if err := s.tail.Truncate(segment.size); err == nil {
+		return
+	}
+
+	if err := s.tail.Close(); err != nil {
+		log.Println(err.Error())
+	}
+	s.tail = nil
+	segment.size += n
+	s.size += n
+}
+
+// peek returns the oldest entry without removing it, io.EOF is returned if the spool is empty.
+func (s *spool) peek() ([]byte, error) {
+	if s.hasPeek {
+		return s.peeked, nil
+	}
+
+	for len(s.segments) > 0 {
+		if s.head == nil {
+			if err := s.openHead(); err != nil {
+				return nil, err
+			}
+		}
+
+		if _, err := io.ReadFull(s.reader, s.header[:]); err != nil {
+			// io.ErrUnexpectedEOF means the entry was partially written, like the process crashed
+			s.removeHead()
+			continue
+		}
+
+		// the records are never larger than a segment, a larger length means the segment is corrupted
+		length := binary.BigEndian.Uint32(s.header[:])
+		if length > spoolSegmentSize-spoolHeaderSize {
+			log.Printf("spool segment %s is corrupted, dropping the rest of it", s.segments[0].filename)
+			s.removeHead()
+			continue
+		}
+
+		data := make([]byte, length)
+		if _, err := io.ReadFull(s.reader, data); err != nil {
+			s.removeHead()
+			continue
+		}
+
+		s.peeked = data
+		s.hasPeek = true
+		return data, nil
+	}
+
+	return nil, io.EOF
+}
+
+// commit removes the entry returned by peek.
+func (s *spool) commit() {
+	s.peeked = nil
+	s.hasPeek = false
+}
+
+// close closes the open files, the segments are kept to be replayed by the next run.
+func (s *spool) close() error {
+	var err error
+	if s.tail != nil {
+		err = s.tail.Close()
+		s.tail = nil
+	}
+	if s.head != nil {
+		if closeErr := s.head.Close(); err == nil {
+			err = closeErr
+		}
+		s.head = nil
+	}
+
+	return err
+}
+
+func (s *spool) segmentName(seq uint64) string {
+	return path.Join(s.dir, fmt.Sprintf("%020d%s", seq, spoolExt))
+}
+
+func (s *spool) openSegment() error {
+	if s.tail != nil {
+		if err := s.tail.Close(); err != nil {
+			log.Println(err.Error())
+		}
+		s.tail = nil
+	}
+
+	filename := s.segmentName(s.nextSeq)
+	fp, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, defaultFileMode)
+	if err != nil {
+		return err
+	}
+	fs.CloseOnExec(fp)
+
+	s.nextSeq++
+	s.tail = fp
+	s.segments = append(s.segments, &spoolSegment{
+		filename: filename,
+	})
+
+	return nil
+}
+
+// openHead opens the oldest segment for replaying, if it's still being appended, the appending
+// is moved to a new segment.
+func (s *spool) openHead() error {
+	if len(s.segments) == 1 && s.tail != nil {
+		if err := s.tail.Close(); err != nil {
+			log.Println(err.Error())
+		}
+		s.tail = nil
+	}
+
+	fp, err := os.Open(s.segments[0].filename)
+	if err != nil {
+		return err
+	}
+	fs.CloseOnExec(fp)
+
+	s.head = fp
+	s.reader = bufio.NewReader(fp)
+	return nil
+}
+
+// removeHead deletes the oldest segment after it's replayed.
+func (s *spool) removeHead() {
+	segment := s.segments[0]
+	if s.head != nil {
+		if err := s.head.Close(); err != nil {
+			log.Println(err.Error())
+		}
+		s.head = nil
+		s.reader = nil
+	}
+	if len(s.segments) == 1 && s.tail != nil {
+		if err := s.tail.Close(); err != nil {
+			log.Println(err.Error())
+		}
+		s.tail = nil
+	}
+
+	if err := os.Remove(segment.filename); err != nil && !os.IsNotExist(err) {
+		log.Printf("remove spool segment %s failed: %s", segment.filename, err.Error())
+	}
+	s.segments = s.segments[1:]
+	s.size -= segment.size
+}
+
+// dropOldest discards the oldest segment to make room for the new entries.
+func (s *spool) dropOldest() {
+	log.Printf("spool %s is full, dropping %d bytes of log entries in %s",
+		s.dir, s.segments[0].size, s.segments[0].filename)
+	s.commit()
+	s.removeHead()
+}
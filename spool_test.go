@@ -0,0 +1,190 @@
+package logx
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpool(t *testing.T) {
+	s, err := newSpool(t.TempDir(), megaBytes)
+	assert.Nil(t, err)
+	s.segmentSize = 20
+
+	assert.True(t, s.empty())
+	_, err = s.peek()
+	assert.Equal(t, io.EOF, err)
+
+	// each entry takes 10 bytes, so every 2 entries make a segment
+	for i := 0; i < 5; i++ {
+		assert.Nil(t, s.append([]byte(fmt.Sprintf("hello%d", i))))
+	}
+	assert.Equal(t, 3, len(s.segments))
+	assert.Equal(t, int64(50), s.size)
+
+	for i := 0; i < 3; i++ {
+		data, err := s.peek()
+		assert.Nil(t, err)
+		assert.Equal(t, fmt.Sprintf("hello%d", i), string(data))
+		// peek again before commit returns the same entry
+		data, err = s.peek()
+		assert.Nil(t, err)
+		assert.Equal(t, fmt.Sprintf("hello%d", i), string(data))
+		s.commit()
+	}
+
+	// appending while replaying keeps the order
+	assert.Nil(t, s.append([]byte("hello5")))
+	for i := 3; i < 6; i++ {
+		data, err := s.peek()
+		assert.Nil(t, err)
+		assert.Equal(t, fmt.Sprintf("hello%d", i), string(data))
+		s.commit()
+	}
+
+	_, err = s.peek()
+	assert.Equal(t, io.EOF, err)
+	assert.True(t, s.empty())
+	assert.Equal(t, int64(0), s.size)
+	assert.Nil(t, s.close())
+
+	entries, err := os.ReadDir(s.dir)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(entries))
+}
+
+func TestSpoolReopen(t *testing.T) {
+	dir := t.TempDir()
+	s, err := newSpool(dir, megaBytes)
+	assert.Nil(t, err)
+	assert.Nil(t, s.append([]byte("first")))
+	assert.Nil(t, s.append([]byte("second")))
+	assert.Nil(t, s.close())
+
+	// a partially written entry is skipped
+	fp, err := os.OpenFile(s.segmentName(1), os.O_APPEND|os.O_WRONLY, defaultFileMode)
+	assert.Nil(t, err)
+	_, err = fp.Write([]byte{0, 0, 0, 9, 'x'})
+	assert.Nil(t, err)
+	assert.Nil(t, fp.Close())
+	assert.Nil(t, os.WriteFile(path.Join(dir, "unknown.seg"), []byte("ignored"), defaultFileMode))
+
+	s, err = newSpool(dir, megaBytes)
+	assert.Nil(t, err)
+	defer s.close()
+	assert.Equal(t, uint64(2), s.nextSeq)
+	assert.Nil(t, s.append([]byte("third")))
+
+	for _, expect := range []string{"first", "second", "third"} {
+		data, err := s.peek()
+		assert.Nil(t, err)
+		assert.Equal(t, expect, string(data))
+		s.commit()
+	}
+	_, err = s.peek()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestSpoolCorrupted(t *testing.T) {
+	dir := t.TempDir()
+	s, err := newSpool(dir, megaBytes)
+	assert.Nil(t, err)
+	assert.Nil(t, s.append([]byte("first")))
+	assert.Nil(t, s.close())
+
+	fp, err := os.OpenFile(s.segmentName(1), os.O_APPEND|os.O_WRONLY, defaultFileMode)
+	assert.Nil(t, err)
+	_, err = fp.Write([]byte{0xff, 0xff, 0xff, 0xff, 'x'})
+	assert.Nil(t, err)
+	assert.Nil(t, fp.Close())
+
+	s, err = newSpool(dir, megaBytes)
+	assert.Nil(t, err)
+	defer s.close()
+	data, err := s.peek()
+	assert.Nil(t, err)
+	assert.Equal(t, "first", string(data))
+	s.commit()
+	_, err = s.peek()
+	assert.Equal(t, io.EOF, err)
+	assert.True(t, s.empty())
+}
+
+func TestSpoolAppendFailed(t *testing.T) {
+	s, err := newSpool(t.TempDir(), megaBytes)
+	assert.Nil(t, err)
+	defer s.close()
+	assert.Nil(t, s.append([]byte("first")))
+
+	// the header is written but the data is not, like the disk is full
+	tail := &failingSpoolFile{spoolFile: s.tail}
+	s.tail = tail
+	assert.NotNil(t, s.append([]byte("failed")))
+	assert.Equal(t, int64(9), s.size)
+	s.tail = tail.spoolFile
+	assert.Nil(t, s.append([]byte("second")))
+
+	// the partial record can't be truncated, the segment is closed
+	tail = &failingSpoolFile{spoolFile: s.tail, truncateErr: errors.New("truncate")}
+	s.tail = tail
+	assert.NotNil(t, s.append([]byte("failed")))
+	assert.Nil(t, s.tail)
+	assert.Nil(t, s.append([]byte("third")))
+	assert.Equal(t, 2, len(s.segments))
+
+	for _, expect := range []string{"first", "second", "third"} {
+		data, err := s.peek()
+		assert.Nil(t, err)
+		assert.Equal(t, expect, string(data))
+		s.commit()
+	}
+	_, err = s.peek()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestSpoolFull(t *testing.T) {
+	s, err := newSpool(t.TempDir(), 30)
+	assert.Nil(t, err)
+	defer s.close()
+	s.segmentSize = 20
+
+	assert.Equal(t, errSpoolEntryTooLarge, s.append(make([]byte, 30)))
+	for i := 0; i < 4; i++ {
+		assert.Nil(t, s.append([]byte(fmt.Sprintf("hello%d", i))))
+	}
+
+	// the oldest segment with hello0 and hello1 is dropped
+	assert.Equal(t, int64(20), s.size)
+	data, err := s.peek()
+	assert.Nil(t, err)
+	assert.Equal(t, "hello2", string(data))
+}
+
+// failingSpoolFile fails the writes after the first one.
+type failingSpoolFile struct {
+	spoolFile
+	writes      int
+	truncateErr error
+}
+
+func (f *failingSpoolFile) Write(p []byte) (int, error) {
+	f.writes++
+	if f.writes > 1 {
+		return 0, errors.New("no space left on device")
+	}
+
+	return f.spoolFile.Write(p)
+}
+
+func (f *failingSpoolFile) Truncate(size int64) error {
+	if f.truncateErr != nil {
+		return f.truncateErr
+	}
+
+	return f.spoolFile.Truncate(size)
+}
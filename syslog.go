@@ -0,0 +1,319 @@
+package logx
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+const (
+	syslogMode = "syslog"
+
+	unixNetwork     = "unix"
+	unixgramNetwork = "unixgram"
+	udpNetwork      = "udp"
+	tcpNetwork      = "tcp"
+
+	defaultSyslogAddress = "/dev/log"
+	syslogVersion        = 1
+	syslogTimeFormat     = "2006-01-02T15:04:05.000000Z07:00"
+	// syslogSDID is the SD-ID of the structured data, 32473 is the enterprise number reserved for documentation
+	syslogSDID     = "logx@32473"
+	syslogNilValue = "-"
+	maxHostnameLen = 255
+	maxAppNameLen  = 48
+	maxSDParamLen  = 32
+)
+
+var (
+	syslogFacilities = map[string]int{
+		"kern":     0,
+		"user":     1,
+		"mail":     2,
+		"daemon":   3,
+		"auth":     4,
+		"syslog":   5,
+		"lpr":      6,
+		"news":     7,
+		"uucp":     8,
+		"cron":     9,
+		"authpriv": 10,
+		"ftp":      11,
+		"local0":   16,
+		"local1":   17,
+		"local2":   18,
+		"local3":   19,
+		"local4":   20,
+		"local5":   21,
+		"local6":   22,
+		"local7":   23,
+	}
+
+	// syslogSeverities maps the logx levels to the syslog severities
+	syslogSeverities = map[string]int{
+		levelDebug: 7,
+		levelInfo:  6,
+		levelWarn:  4,
+		levelError: 3,
+		levelFatal: 2,
+	}
+)
+
+type (
+	// syslogEncoder encodes entries as RFC 5424 messages, the fields and the caller are written as
+	// the structured data, the content as the message.
+	syslogEncoder struct {
+		facility int
+		hostname string
+		appName  string
+		procID   string
+	}
+
+	// syslogConn frames the encoded messages for the syslog server, it's dialed and reconnected by
+	// networkLogger, which queues the entries and writes them to stderr while the server is unreachable.
+	syslogConn struct {
+		net.Conn
+		network string
+	}
+)
+
+// newSyslogWriter creates the Writer of syslog mode, which sends the entries to c.Address over c.Network,
+// or /dev/log if not set.
+func newSyslogWriter(c LogConf) (Writer, error) {
+	l, err := newSyslogLogger(c)
+	if err != nil {
+		return nil, err
+	}
+	l.startWorker()
+
+	return &defaultWriter{
+		lw: l,
+		conf: &encoderConf{
+			encoder: newSyslogEncoder(c),
+		},
+	}, nil
+}
+
+// newSyslogLogger returns the networkLogger connected to the syslog server, the worker is not started yet.
+func newSyslogLogger(c LogConf) (*networkLogger, error) {
+	network := c.Network
+	if len(network) == 0 {
+		network = unixNetwork
+	}
+	address := c.Address
+	if len(address) == 0 {
+		address = defaultSyslogAddress
+	}
+
+	l := newConnLogger(network, address, dialSyslog, c)
+	if err := l.openSpool(c); err != nil {
+		return nil, err
+	}
+
+	// connect right away to report the unreachable server on creation, like log/syslog,
+	// unless the entries are spooled until the server is up
+	if err := l.connect(); err != nil && l.spool == nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+func newSyslogEncoder(c LogConf) *syslogEncoder {
+	facility, ok := syslogFacilities[c.Facility]
+	if !ok {
+		facility = syslogFacilities["user"]
+	}
+
+	hostname, _ := os.Hostname()
+	appName := c.AppName
+	if len(appName) == 0 {
+		appName = filepath.Base(os.Args[0])
+	}
+
+	return &syslogEncoder{
+		facility: facility,
+		hostname: syslogHeaderValue(hostname, maxHostnameLen),
+		appName:  syslogHeaderValue(appName, maxAppNameLen),
+		procID:   strconv.Itoa(os.Getpid()),
+	}
+}
+
+func (e *syslogEncoder) Encode(buf *bytes.Buffer, entry Entry) error {
+	severity, ok := syslogSeverities[entry.Level]
+	if !ok {
+		severity = syslogSeverities[levelInfo]
+	}
+
+	// HEADER = PRI VERSION SP TIMESTAMP SP HOSTNAME SP APP-NAME SP PROCID SP MSGID
+	fmt.Fprintf(buf, "<%d>%d ", e.facility*8+severity, syslogVersion)
+	var scratch [64]byte
+	buf.Write(entry.Time.AppendFormat(scratch[:0], syslogTimeFormat))
+	buf.WriteByte(' ')
+	buf.WriteString(e.hostname)
+	buf.WriteByte(' ')
+	buf.WriteString(e.appName)
+	buf.WriteByte(' ')
+	buf.WriteString(e.procID)
+	buf.WriteByte(' ')
+	buf.WriteString(syslogNilValue)
+	buf.WriteByte(' ')
+
+	if err := writeSyslogStructuredData(buf, entry); err != nil {
+		return err
+	}
+
+	msg, err := syslogMessage(entry.Content)
+	if err != nil {
+		return err
+	}
+	if len(msg) > 0 {
+		buf.WriteByte(' ')
+		buf.WriteString(msg)
+	}
+
+	return nil
+}
+
+func writeSyslogStructuredData(buf *bytes.Buffer, entry Entry) error {
+	if len(entry.Caller) == 0 && len(entry.Fields) == 0 {
+		buf.WriteString(syslogNilValue)
+		return nil
+	}
+
+	buf.WriteByte('[')
+	buf.WriteString(syslogSDID)
+	if len(entry.Caller) > 0 {
+		writeSyslogParam(buf, callerKey, entry.Caller)
+	}
+	for _, field := range entry.Fields {
+		val, err := syslogMessage(field.Value)
+		if err != nil {
+			return err
+		}
+		writeSyslogParam(buf, field.Key, val)
+	}
+	buf.WriteByte(']')
+
+	return nil
+}
+
+// writeSyslogParam writes PARAM-NAME="PARAM-VALUE", the invalid characters of the name are replaced
+// with underscores, and ", \ and ] in the value are escaped.
+func writeSyslogParam(buf *bytes.Buffer, name, val string) {
+	buf.WriteByte(' ')
+	if len(name) == 0 {
+		name = "_"
+	}
+	if len(name) > maxSDParamLen {
+		name = name[:maxSDParamLen]
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if c <= ' ' || c > '~' || c == '=' || c == ']' || c == '"' {
+			c = '_'
+		}
+		buf.WriteByte(c)
+	}
+
+	buf.WriteString(`="`)
+	for i := 0; i < len(val); i++ {
+		switch c := val[i]; c {
+		case '"', '\\', ']':
+			buf.WriteByte('\\')
+			buf.WriteByte(c)
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	buf.WriteByte('"')
+}
+
+func syslogMessage(val interface{}) (string, error) {
+	switch v := val.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case error:
+		return v.Error(), nil
+	case fmt.Stringer:
+		return v.String(), nil
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprint(v), nil
+	default:
+		content, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return string(content), nil
+	}
+}
+
+// syslogHeaderValue keeps the printable ascii characters of val, at most n of them,
+// or returns the nil value if nothing left.
+func syslogHeaderValue(val string, n int) string {
+	var buf bytes.Buffer
+	for i := 0; i < len(val) && buf.Len() < n; i++ {
+		if c := val[i]; c > ' ' && c <= '~' {
+			buf.WriteByte(c)
+		}
+	}
+
+	if buf.Len() == 0 {
+		return syslogNilValue
+	}
+
+	return buf.String()
+}
+
+// dialSyslog connects to the syslog server, for unix sockets both datagram and stream sockets are tried
+// like log/syslog.
+func dialSyslog(network, address string, timeout time.Duration) (networkConn, error) {
+	if network == unixNetwork {
+		for _, network := range []string{unixgramNetwork, unixNetwork} {
+			conn, err := net.DialTimeout(network, address, timeout)
+			if err == nil {
+				return &syslogConn{Conn: conn, network: network}, nil
+			}
+		}
+
+		return nil, fmt.Errorf("logx: unix syslog delivery error: %s", address)
+	}
+
+	conn, err := net.DialTimeout(network, address, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	return &syslogConn{Conn: conn, network: network}, nil
+}
+
+// Write sends one encoded entry, the trailing newline added by output is replaced by the framing
+// of the network: octet counting over tcp as RFC 6587, newline terminated over unix stream sockets,
+// and as is over the datagram sockets.
+func (c *syslogConn) Write(p []byte) (int, error) {
+	msg := bytes.TrimSuffix(p, []byte{'\n'})
+
+	var err error
+	switch c.network {
+	case tcpNetwork:
+		_, err = fmt.Fprintf(c.Conn, "%d %s", len(msg), msg)
+	case unixNetwork:
+		// the newlines in msg, like the stacks of the error entries, are escaped to keep it one message
+		msg = bytes.ReplaceAll(msg, []byte{'\n'}, []byte(`\n`))
+		_, err = c.Conn.Write(append(msg, '\n'))
+	default:
+		_, err = c.Conn.Write(msg)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
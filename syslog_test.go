@@ -0,0 +1,321 @@
+package logx
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyslogEncoder(t *testing.T) {
+	e := newSyslogEncoder(LogConf{AppName: "my app", Facility: "local0"})
+	e.hostname = "host"
+	e.procID = "42"
+	now := time.Date(2026, 10, 16, 8, 0, 0, 123456000, time.UTC)
+
+	var buf bytes.Buffer
+	assert.Nil(t, e.Encode(&buf, Entry{
+		Time:    now,
+		Level:   levelError,
+		Caller:  "logx/syslog_test.go:1",
+		Content: "hello there",
+		Fields: []LogField{
+			String("request id", "r1"),
+			String("quote", `a"b\c]d`),
+			Err(errors.New("boom")),
+			Any("nested", map[string]int{"a": 1}),
+		},
+	}))
+	assert.Equal(t, `<131>1 2026-10-16T08:00:00.123456Z host myapp 42 - `+
+		`[logx@32473 caller="logx/syslog_test.go:1" request_id="r1" quote="a\"b\\c\]d" error="boom" nested="{\"a\":1}"]`+
+		` hello there`, buf.String())
+
+	buf.Reset()
+	assert.Nil(t, e.Encode(&buf, Entry{Time: now, Level: levelDebug}))
+	assert.Equal(t, `<135>1 2026-10-16T08:00:00.123456Z host myapp 42 - -`, buf.String())
+
+	assert.NotNil(t, e.Encode(&buf, Entry{Content: make(chan int)}))
+	assert.NotNil(t, e.Encode(&buf, Entry{Fields: []LogField{Any("ch", make(chan int))}}))
+}
+
+func TestSyslogEncoderDefaults(t *testing.T) {
+	e := newSyslogEncoder(LogConf{})
+	assert.Equal(t, syslogFacilities["user"], e.facility)
+	assert.Equal(t, strconv.Itoa(os.Getpid()), e.procID)
+	assert.Equal(t, syslogNilValue, syslogHeaderValue(" \t", maxAppNameLen))
+	assert.Equal(t, "ab", syslogHeaderValue("abc", 2))
+}
+
+func TestSyslogWriterUDP(t *testing.T) {
+	conn, err := net.ListenPacket(udpNetwork, "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	l, err := New(LogConf{
+		Mode:     syslogMode,
+		Network:  udpNetwork,
+		Address:  conn.LocalAddr().String(),
+		AppName:  "logx",
+		Facility: "daemon",
+	})
+	assert.Nil(t, err)
+	defer l.Close()
+
+	file, line := getFileLine()
+	l.Warnw("hello there", String("foo", "bar"))
+
+	buf := make([]byte, 4096)
+	assert.Nil(t, conn.SetReadDeadline(time.Now().Add(time.Second*5)))
+	n, _, err := conn.ReadFrom(buf)
+	assert.Nil(t, err)
+	msg := string(buf[:n])
+	assert.Regexp(t, regexp.MustCompile(`^<28>1 \S+ \S+ logx \d+ - \[logx@32473 caller="[^"]+" foo="bar"\] hello there$`), msg)
+	assert.Contains(t, msg, fmt.Sprintf("%s:%d", file, line+1))
+}
+
+func TestSyslogWriterTCP(t *testing.T) {
+	listener, err := net.Listen(tcpNetwork, "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+
+	received := make(chan string, 2)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		for i := 0; i < 2; i++ {
+			received <- readOctetCounted(t, reader)
+		}
+	}()
+
+	l, err := New(LogConf{
+		Mode:    syslogMode,
+		Network: tcpNetwork,
+		Address: listener.Addr().String(),
+	})
+	assert.Nil(t, err)
+	defer l.Close()
+
+	l.Info("first line")
+	l.Infow("second\nline", Int("count", 2))
+	assert.True(t, strings.HasSuffix(<-received, `"] first line`))
+	assert.True(t, strings.HasSuffix(<-received, ` count="2"] second`+"\nline"))
+}
+
+func TestSyslogWriterUnixgram(t *testing.T) {
+	address := path.Join(t.TempDir(), "log.sock")
+	conn, err := net.ListenPacket(unixgramNetwork, address)
+	if err != nil {
+		t.Skip(err)
+	}
+	defer conn.Close()
+
+	c, err := dialSyslog(unixNetwork, address, time.Second)
+	assert.Nil(t, err)
+	defer c.Close()
+	assert.Equal(t, unixgramNetwork, c.(*syslogConn).network)
+
+	_, err = c.Write([]byte("<14>1 hello\n"))
+	assert.Nil(t, err)
+	buf := make([]byte, 1024)
+	assert.Nil(t, conn.SetReadDeadline(time.Now().Add(time.Second*5)))
+	n, _, err := conn.ReadFrom(buf)
+	assert.Nil(t, err)
+	assert.Equal(t, "<14>1 hello", string(buf[:n]))
+}
+
+func TestSyslogWriterUnixStream(t *testing.T) {
+	address := path.Join(t.TempDir(), "log.sock")
+	listener, err := net.Listen(unixNetwork, address)
+	if err != nil {
+		t.Skip(err)
+	}
+	defer listener.Close()
+
+	lines := make(chan string, 1)
+	go acceptLines(listener, lines)
+
+	c, err := dialSyslog(unixNetwork, address, time.Second)
+	assert.Nil(t, err)
+	defer c.Close()
+	assert.Equal(t, unixNetwork, c.(*syslogConn).network)
+
+	// the stack of the error entries must not be split into several messages
+	_, err = c.Write([]byte("<11>1 failed stack=\"main.go:1\nmain.go:2\"\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, `<11>1 failed stack="main.go:1\nmain.go:2"`, receiveLine(t, lines))
+}
+
+func TestSyslogWriterReconnect(t *testing.T) {
+	listener, err := net.Listen(tcpNetwork, "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+
+	received := make(chan string, 1)
+	go func() {
+		// the first connection is closed immediately, the writer should reconnect
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		conn.Close()
+
+		conn, err = listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		received <- readOctetCounted(t, bufio.NewReader(conn))
+	}()
+
+	l, err := newSyslogLogger(LogConf{Network: tcpNetwork, Address: listener.Addr().String()})
+	assert.Nil(t, err)
+	var fallback safeBuffer
+	l.fallback = &fallback
+	l.minBackoff = time.Millisecond
+	l.startWorker()
+	defer l.Close()
+
+	// the write right after the peer closed might succeed, keep writing until reconnected
+	deadline := time.After(time.Second * 5)
+	for {
+		_, err = l.Write([]byte("hello\n"))
+		assert.Nil(t, err)
+
+		select {
+		case msg := <-received:
+			assert.Equal(t, "hello", msg)
+			return
+		case <-deadline:
+			t.Fatal("not reconnected")
+		case <-time.After(time.Millisecond * 10):
+		}
+	}
+}
+
+func TestSyslogWriterServerDown(t *testing.T) {
+	// reserve a port and release it, so that the server is down
+	listener, err := net.Listen(tcpNetwork, "127.0.0.1:0")
+	assert.Nil(t, err)
+	address := listener.Addr().String()
+	assert.Nil(t, listener.Close())
+
+	var dials int32
+	l := newConnLogger(tcpNetwork, address, func(network, address string, timeout time.Duration) (networkConn, error) {
+		atomic.AddInt32(&dials, 1)
+		return dialSyslog(network, address, timeout)
+	}, LogConf{})
+	var fallback safeBuffer
+	l.fallback = &fallback
+	l.minBackoff = time.Hour
+	l.startWorker()
+
+	w := &defaultWriter{lw: l, conf: &encoderConf{encoder: newSyslogEncoder(LogConf{})}}
+	for i := 0; i < 10; i++ {
+		w.Info(fmt.Sprintf("entry %d", i))
+	}
+	assert.Nil(t, l.Close())
+
+	// no redial until the backoff expires, and the entries are written to the fallback
+	assert.Equal(t, int32(1), atomic.LoadInt32(&dials))
+	for i := 0; i < 10; i++ {
+		assert.Contains(t, fallback.String(), fmt.Sprintf("entry %d\n", i))
+	}
+}
+
+func TestSyslogWriterSpool(t *testing.T) {
+	// reserve a port and release it, the server is started after some entries are spooled
+	listener, err := net.Listen(tcpNetwork, "127.0.0.1:0")
+	assert.Nil(t, err)
+	address := listener.Addr().String()
+	assert.Nil(t, listener.Close())
+
+	// the unreachable server is not an error on creation if spooling
+	c := LogConf{Network: tcpNetwork, Address: address, Path: t.TempDir(), Spool: true}
+	l, err := newSyslogLogger(c)
+	assert.Nil(t, err)
+	var fallback safeBuffer
+	l.fallback = &fallback
+	l.minBackoff = time.Millisecond
+	l.nextDial = time.Time{}
+	l.retryInterval = time.Millisecond * 10
+	l.startWorker()
+
+	for i := 0; i < 3; i++ {
+		_, err = l.Write([]byte(fmt.Sprintf("hello%d\n", i)))
+		assert.Nil(t, err)
+	}
+	assert.Eventually(t, func() bool {
+		entries, err := os.ReadDir(spoolPath(c, tcpNetwork, address))
+		return err == nil && len(entries) > 0
+	}, time.Second*5, time.Millisecond*10)
+
+	listener, err = net.Listen(tcpNetwork, address)
+	if err != nil {
+		t.Skip(err)
+	}
+	defer listener.Close()
+	received := make(chan string, 3)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		for i := 0; i < 3; i++ {
+			received <- readOctetCounted(t, reader)
+		}
+	}()
+
+	for i := 0; i < 3; i++ {
+		select {
+		case msg := <-received:
+			assert.Equal(t, fmt.Sprintf("hello%d", i), msg)
+		case <-time.After(time.Second * 5):
+			t.Fatal("spooled entries not replayed")
+		}
+	}
+	assert.Nil(t, l.Close())
+	assert.Equal(t, "", fallback.String())
+}
+
+func TestSyslogWriterDialError(t *testing.T) {
+	_, err := newSyslogLogger(LogConf{Address: path.Join(t.TempDir(), "none.sock")})
+	assert.NotNil(t, err)
+
+	assert.EqualError(t, LogConf{Mode: syslogMode, Network: tcpNetwork}.Validate(),
+		`logx: invalid LogConf: Address: must be set for network tcp`)
+}
+
+func readOctetCounted(t *testing.T, reader *bufio.Reader) string {
+	size, err := reader.ReadString(' ')
+	if err != nil {
+		return ""
+	}
+
+	n, err := strconv.Atoi(strings.TrimSpace(size))
+	assert.Nil(t, err)
+	buf := make([]byte, n)
+	if _, err = io.ReadFull(reader, buf); err != nil {
+		return ""
+	}
+
+	return string(buf)
+}
@@ -0,0 +1,369 @@
+package logx
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// teeQueueSize is the number of entries queued for each writer of Tee.
+const teeQueueSize = defaultQueueSize
+
+type (
+	// teeWriter forwards every entry to all its writers, each writer is written by its own goroutine,
+	// so that a writer that blocks or panics doesn't hold up the others.
+	teeWriter struct {
+		sinks []*teeSink
+	}
+
+	// teeSink hands the entries over to writer in its own goroutine. The entries are dropped if
+	// the queue is full, except the fatal ones, since the process exits right after them.
+	teeSink struct {
+		// dropped counts the entries discarded because the queue was full, accessed atomically,
+		// kept as the first field to be 64-bit aligned on 32-bit platforms
+		dropped uint64
+		writer  Writer
+		entries chan teeEntry
+		stopped chan struct{}
+		// lock guards closed, so that no entry is queued after entries is closed
+		lock   sync.RWMutex
+		closed bool
+	}
+
+	// teeEntry is an entry queued for a writer of Tee, or a flush request if flushed is not nil.
+	teeEntry struct {
+		level  uint32
+		caller string
+		v      interface{}
+		fields []LogField
+		// flushed is closed after the entries queued before it are written
+		flushed chan struct{}
+	}
+
+	// levelWriter forwards the entries at or above level to writer.
+	levelWriter struct {
+		writer Writer
+		level  uint32
+	}
+
+	// callerWriter is implemented by the writers that take the caller of the logging call from the
+	// writers wrapping them, because the wrapped ones are called deeper than callerDepth.
+	callerWriter interface {
+		writeWithCaller(level uint32, caller string, v interface{}, fields []LogField)
+	}
+
+	// writerErrors joins the errors returned by the writers of Tee.
+	writerErrors []error
+)
+
+// Tee 返回将每条日志写入所有 writers 的 Writer，可以通过 SetWriter 使用。
+// 每个 writer 可以用 LevelWriter 设置自己的最低级别，用 NewWriterWithConf 设置自己的编码方式；
+// 每个 writer 有自己的队列和 goroutine，某个 writer 阻塞或 panic 不影响其他 writer，
+// 队列满时丢弃该 writer 的日志并计入 Dropped，Fatal 日志不会被丢弃。
+// Flush 等待队列中的日志写入后刷新所有 writer，Close 等待队列中的日志写入后关闭所有 writer 并合并返回的错误
+func Tee(writers ...Writer) Writer {
+	t := &teeWriter{}
+	for _, w := range writers {
+		if w != nil {
+			t.sinks = append(t.sinks, newTeeSink(w))
+		}
+	}
+
+	return t
+}
+
+// LevelWriter 返回只写入 level 及以上级别日志的 Writer，level 代替全局日志级别生效
+func LevelWriter(w Writer, level uint32) Writer {
+	return &levelWriter{
+		writer: w,
+		level:  level,
+	}
+}
+
+func (t *teeWriter) Close() error {
+	// stop all the queues first, so that they are drained concurrently
+	for _, s := range t.sinks {
+		s.stop()
+	}
+
+	var errs writerErrors
+	for _, s := range t.sinks {
+		<-s.stopped
+		if err := s.writer.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errs.err()
+}
+
+// Flush flushes all the writers after the entries queued for them are written.
+func (t *teeWriter) Flush() error {
+	var errs writerErrors
+	for _, s := range t.sinks {
+		if err := s.flush(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errs.err()
+}
+
+// Dropped returns the number of entries dropped by Tee and all the writers.
+func (t *teeWriter) Dropped() uint64 {
+	var dropped uint64
+	for _, s := range t.sinks {
+		dropped += atomic.LoadUint64(&s.dropped)
+		if c, ok := s.writer.(dropCounter); ok {
+			dropped += c.Dropped()
+		}
+	}
+
+	return dropped
+}
+
+func (t *teeWriter) Debug(v interface{}, fields ...LogField) {
+	t.writeWithCaller(DebugLevel, loggingCaller(), v, fields)
+}
+
+func (t *teeWriter) Error(v interface{}, fields ...LogField) {
+	t.writeWithCaller(ErrorLevel, loggingCaller(), v, fields)
+}
+
+func (t *teeWriter) Fatal(v interface{}, fields ...LogField) {
+	t.writeWithCaller(FatalLevel, loggingCaller(), v, fields)
+}
+
+func (t *teeWriter) Info(v interface{}, fields ...LogField) {
+	t.writeWithCaller(InfoLevel, loggingCaller(), v, fields)
+}
+
+func (t *teeWriter) Warn(v interface{}, fields ...LogField) {
+	t.writeWithCaller(WarnLevel, loggingCaller(), v, fields)
+}
+
+// shallLog checks if any of the writers logs level.
+func (t *teeWriter) shallLog(level uint32) bool {
+	for _, s := range t.sinks {
+		if shallLogTo(s.writer, level) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (t *teeWriter) writeWithCaller(level uint32, caller string, v interface{}, fields []LogField) {
+	// the fields are written later by the other goroutines, copy them in case the caller reuses them
+	fields = append([]LogField(nil), fields...)
+	for _, s := range t.sinks {
+		if shallLogTo(s.writer, level) {
+			s.put(teeEntry{
+				level:  level,
+				caller: caller,
+				v:      v,
+				fields: fields,
+			})
+		}
+	}
+}
+
+func newTeeSink(w Writer) *teeSink {
+	s := &teeSink{
+		writer:  w,
+		entries: make(chan teeEntry, teeQueueSize),
+		stopped: make(chan struct{}),
+	}
+	go s.run()
+
+	return s
+}
+
+// flush waits for the queued entries to be written, then flushes writer.
+func (s *teeSink) flush() error {
+	flushed := make(chan struct{})
+	s.lock.RLock()
+	if s.closed {
+		close(flushed)
+	} else {
+		s.entries <- teeEntry{flushed: flushed}
+	}
+	s.lock.RUnlock()
+
+	<-flushed
+	return flushWriter(s.writer)
+}
+
+// put queues e for writer, e is written directly if the sink is stopped,
+// and writer decides how to handle the entries after it's closed.
+func (s *teeSink) put(e teeEntry) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if s.closed {
+		safeWrite(s.writer, e.level, e.caller, e.v, e.fields)
+		return
+	}
+
+	if e.level == FatalLevel {
+		s.entries <- e
+		return
+	}
+
+	select {
+	case s.entries <- e:
+	default:
+		atomic.AddUint64(&s.dropped, 1)
+	}
+}
+
+func (s *teeSink) run() {
+	defer close(s.stopped)
+
+	for e := range s.entries {
+		if e.flushed != nil {
+			close(e.flushed)
+		} else {
+			safeWrite(s.writer, e.level, e.caller, e.v, e.fields)
+		}
+	}
+}
+
+// stop stops accepting entries, the worker exits after writing the queued ones.
+func (s *teeSink) stop() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if !s.closed {
+		s.closed = true
+		close(s.entries)
+	}
+}
+
+func (w *levelWriter) Close() error {
+	return w.writer.Close()
+}
+
+func (w *levelWriter) Flush() error {
+	return flushWriter(w.writer)
+}
+
+func (w *levelWriter) Dropped() uint64 {
+	if c, ok := w.writer.(dropCounter); ok {
+		return c.Dropped()
+	}
+
+	return 0
+}
+
+func (w *levelWriter) Debug(v interface{}, fields ...LogField) {
+	w.writeWithCaller(DebugLevel, loggingCaller(), v, fields)
+}
+
+func (w *levelWriter) Error(v interface{}, fields ...LogField) {
+	w.writeWithCaller(ErrorLevel, loggingCaller(), v, fields)
+}
+
+func (w *levelWriter) Fatal(v interface{}, fields ...LogField) {
+	w.writeWithCaller(FatalLevel, loggingCaller(), v, fields)
+}
+
+func (w *levelWriter) Info(v interface{}, fields ...LogField) {
+	w.writeWithCaller(InfoLevel, loggingCaller(), v, fields)
+}
+
+func (w *levelWriter) Warn(v interface{}, fields ...LogField) {
+	w.writeWithCaller(WarnLevel, loggingCaller(), v, fields)
+}
+
+func (w *levelWriter) writeWithCaller(level uint32, caller string, v interface{}, fields []LogField) {
+	if w.shallLog(level) {
+		writeTo(w.writer, level, caller, v, fields)
+	}
+}
+
+func (w *levelWriter) shallLog(level uint32) bool {
+	return level >= w.level
+}
+
+func (e writerErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+// Is checks if any of the errors matches target, for errors.Is.
+func (e writerErrors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// As finds the first error that matches target, for errors.As.
+func (e writerErrors) As(target interface{}) bool {
+	for _, err := range e {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (e writerErrors) err() error {
+	switch len(e) {
+	case 0:
+		return nil
+	case 1:
+		return e[0]
+	default:
+		return e
+	}
+}
+
+// loggingCaller returns the caller of the logging call, it must be called directly by the logging
+// methods of the Writer, at the same depth as output.
+func loggingCaller() string {
+	return getCaller(callerDepth)
+}
+
+// writeTo writes the entry to w, with caller if w takes it.
+func writeTo(w Writer, level uint32, caller string, v interface{}, fields []LogField) {
+	if cw, ok := w.(callerWriter); ok {
+		cw.writeWithCaller(level, caller, v, fields)
+		return
+	}
+
+	switch level {
+	case DebugLevel:
+		w.Debug(v, fields...)
+	case InfoLevel:
+		w.Info(v, fields...)
+	case WarnLevel:
+		w.Warn(v, fields...)
+	case ErrorLevel:
+		w.Error(v, fields...)
+	default:
+		w.Fatal(v, fields...)
+	}
+}
+
+// safeWrite writes the entry to w, the panics are recovered and reported.
+func safeWrite(w Writer, level uint32, caller string, v interface{}, fields []LogField) {
+	defer func() {
+		if p := recover(); p != nil {
+			log.Printf("logx: writer %T panicked: %v", w, p)
+		}
+	}()
+
+	writeTo(w, level, caller, v, fields)
+}
@@ -0,0 +1,215 @@
+package logx
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTee(t *testing.T) {
+	oldLevel := atomic.LoadUint32(&logLevel)
+	SetLevel(InfoLevel)
+	defer SetLevel(oldLevel)
+	var jsonBuf, logfmtBuf bytes.Buffer
+	w := Tee(
+		NewWriterWithConf(&jsonBuf, LogConf{}),
+		nil,
+		LevelWriter(NewWriterWithConf(&logfmtBuf, LogConf{Encoding: logfmtEncoding}), ErrorLevel),
+	)
+	l := &logger{lw: w}
+
+	file, line := getFileLine()
+	l.Infow("hello there", String("foo", "bar"))
+	caller := fmt.Sprintf("%s:%d", file, line+1)
+	assert.Nil(t, l.Flush())
+
+	var entry map[string]interface{}
+	assert.Nil(t, json.Unmarshal(jsonBuf.Bytes(), &entry))
+	assert.Equal(t, "hello there", entry[contentKey])
+	assert.Equal(t, "bar", entry["foo"])
+	assert.True(t, strings.HasSuffix(entry[callerKey].(string), caller))
+	assert.Equal(t, 0, logfmtBuf.Len())
+
+	jsonBuf.Reset()
+	file, line = getFileLine()
+	l.Error("boom")
+	caller = fmt.Sprintf("%s:%d", file, line+1)
+	assert.Nil(t, l.Flush())
+	assert.Contains(t, jsonBuf.String(), `"level":"error"`)
+	assert.Contains(t, logfmtBuf.String(), `level=error msg="boom`)
+	assert.Contains(t, logfmtBuf.String(), caller)
+	assert.NotContains(t, logfmtBuf.String(), "caller=logx/tee.go")
+}
+
+func TestTeeLevels(t *testing.T) {
+	oldLevel := atomic.LoadUint32(&logLevel)
+	SetLevel(InfoLevel)
+	defer SetLevel(oldLevel)
+	var debugBuf, infoBuf bytes.Buffer
+	w := Tee(
+		LevelWriter(NewWriterWithConf(&debugBuf, LogConf{}), DebugLevel),
+		NewWriterWithConf(&infoBuf, LogConf{}),
+	)
+	l := &logger{lw: w}
+
+	// the debug writer decides its own level, the other one follows the global level
+	l.Debug("debug")
+	assert.Nil(t, l.Flush())
+	assert.Contains(t, debugBuf.String(), `"content":"debug"`)
+	assert.Equal(t, 0, infoBuf.Len())
+
+	assert.False(t, shallLogTo(Tee(LevelWriter(NewWriterWithConf(&infoBuf, LogConf{}), ErrorLevel)), WarnLevel))
+}
+
+func TestTeeNested(t *testing.T) {
+	var buf bytes.Buffer
+	l := &logger{lw: Tee(Tee(LevelWriter(NewWriter(&buf), DebugLevel)))}
+
+	file, line := getFileLine()
+	l.Warn("nested")
+	assert.Nil(t, l.Flush())
+	assert.Contains(t, buf.String(), fmt.Sprintf(`%s:%d"`, file, line+1))
+	assert.Equal(t, 1, strings.Count(buf.String(), `"caller"`))
+}
+
+func TestTeeThirdPartyWriter(t *testing.T) {
+	oldLevel := atomic.LoadUint32(&logLevel)
+	SetLevel(InfoLevel)
+	defer SetLevel(oldLevel)
+	w := &recordWriter{mockWriter: new(mockWriter)}
+	l := &logger{lw: Tee(LevelWriter(w, InfoLevel), Tee(w))}
+
+	l.Infow("hello there", String("a", "b"))
+	assert.Nil(t, l.Flush())
+	assert.Equal(t, [][]LogField{{String("a", "b")}, {String("a", "b")}}, w.fields)
+}
+
+func TestTeePanicIsolation(t *testing.T) {
+	oldLevel := atomic.LoadUint32(&logLevel)
+	SetLevel(InfoLevel)
+	defer SetLevel(oldLevel)
+	var buf bytes.Buffer
+	w := Tee(panicWriter{new(mockWriter)}, NewWriter(&buf))
+	w.Info("hello there")
+	w.Debug("debug")
+	w.Warn("warn")
+	w.Error("error")
+	w.Fatal("fatal")
+	assert.Nil(t, w.(flusher).Flush())
+	assert.Contains(t, buf.String(), "hello there")
+	assert.Contains(t, buf.String(), "fatal")
+}
+
+func TestTeeBlockedWriter(t *testing.T) {
+	oldLevel := atomic.LoadUint32(&logLevel)
+	SetLevel(InfoLevel)
+	defer SetLevel(oldLevel)
+	var buf bytes.Buffer
+	blocked := blockWriter{mockWriter: new(mockWriter), unblock: make(chan struct{})}
+	other := NewWriter(&buf)
+	w := Tee(blocked, other)
+
+	// the blocked writer takes one entry, queues teeQueueSize entries and drops the rest
+	// the other writer is flushed on the way to keep its queue from overflowing,
+	// Flush of Tee would wait for the blocked writer
+	total := teeQueueSize + 10
+	for i := 0; i < total; i++ {
+		w.Info("hello there")
+		assert.Nil(t, w.(*teeWriter).sinks[1].flush())
+	}
+	assert.Equal(t, total, strings.Count(buf.String(), "hello there"))
+	assert.True(t, w.(dropCounter).Dropped() >= 9)
+
+	close(blocked.unblock)
+	assert.Nil(t, w.Close())
+}
+
+func TestTeeClose(t *testing.T) {
+	errFirst := errors.New("first")
+	errSecond := errors.New("second")
+
+	assert.Nil(t, Tee(NewWriter(new(bytes.Buffer))).Close())
+	assert.Equal(t, errFirst, Tee(closeErrWriter{err: errFirst}).Close())
+
+	err := Tee(closeErrWriter{err: errFirst}, NewWriter(new(bytes.Buffer)), closeErrWriter{err: errSecond}).Close()
+	assert.EqualError(t, err, "first; second")
+	assert.True(t, errors.Is(err, errSecond))
+	assert.False(t, errors.Is(err, io.EOF))
+	var pathErr *os.PathError
+	err = Tee(closeErrWriter{err: errFirst}, closeErrWriter{err: &os.PathError{Op: "close", Err: errSecond}}).Close()
+	assert.True(t, errors.As(err, &pathErr))
+	assert.Equal(t, "close", pathErr.Op)
+}
+
+func TestTeeFlushAndDropped(t *testing.T) {
+	w := Tee(LevelWriter(mockDropWriter{dropped: 2}, InfoLevel), mockDropWriter{dropped: 3})
+	assert.Equal(t, uint64(5), w.(dropCounter).Dropped())
+	assert.Equal(t, "flush; flush", w.(flusher).Flush().Error())
+	assert.Nil(t, LevelWriter(NewWriter(new(bytes.Buffer)), InfoLevel).(flusher).Flush())
+	assert.Equal(t, uint64(0), LevelWriter(NewWriter(new(bytes.Buffer)), InfoLevel).(dropCounter).Dropped())
+	assert.Nil(t, LevelWriter(NewWriter(new(bytes.Buffer)), InfoLevel).Close())
+}
+
+// recordWriter records the fields passed by the wrapping writers.
+type recordWriter struct {
+	*mockWriter
+	lock   sync.Mutex
+	fields [][]LogField
+}
+
+func (w *recordWriter) Info(v interface{}, fields ...LogField) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.fields = append(w.fields, fields)
+}
+
+// blockWriter blocks the writes until unblocked.
+type blockWriter struct {
+	*mockWriter
+	unblock chan struct{}
+}
+
+func (w blockWriter) Info(v interface{}, fields ...LogField) {
+	<-w.unblock
+}
+
+type panicWriter struct {
+	*mockWriter
+}
+
+func (w panicWriter) Debug(v interface{}, fields ...LogField) { panic("debug") }
+func (w panicWriter) Error(v interface{}, fields ...LogField) { panic("error") }
+func (w panicWriter) Fatal(v interface{}, fields ...LogField) { panic("fatal") }
+func (w panicWriter) Info(v interface{}, fields ...LogField)  { panic("info") }
+func (w panicWriter) Warn(v interface{}, fields ...LogField)  { panic("warn") }
+
+type closeErrWriter struct {
+	*mockWriter
+	err error
+}
+
+func (w closeErrWriter) Close() error {
+	return w.err
+}
+
+type mockDropWriter struct {
+	*mockWriter
+	dropped uint64
+}
+
+func (w mockDropWriter) Dropped() uint64 {
+	return w.dropped
+}
+
+func (w mockDropWriter) Flush() error {
+	return errors.New("flush")
+}
@@ -0,0 +1,103 @@
+package logx
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+type (
+	// A FieldError describes an invalid field of LogConf.
+	FieldError struct {
+		Field  string
+		Value  interface{}
+		Reason string
+	}
+
+	// A ConfError aggregates all the invalid fields of LogConf.
+	ConfError struct {
+		Errors []*FieldError
+	}
+)
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Reason)
+}
+
+func (e *ConfError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		msgs = append(msgs, err.Error())
+	}
+
+	return "logx: invalid LogConf: " + strings.Join(msgs, "; ")
+}
+
+// Validate 校验日志配置，返回包含所有非法字段的 *ConfError。
+// 空字符串表示使用默认值，不做校验
+func (c LogConf) Validate() error {
+	var errs []*FieldError
+
+	rv := reflect.ValueOf(c)
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rv.Field(i)
+		if field.Kind() == reflect.String && field.Len() == 0 {
+			continue
+		}
+
+		if err := checkOptions(parseFieldTag(rt.Field(i)), field); err != nil {
+			errs = append(errs, &FieldError{
+				Field:  rt.Field(i).Name,
+				Value:  field.Interface(),
+				Reason: err.Error(),
+			})
+		}
+	}
+
+	if len(c.Encoding) > 0 {
+		if _, ok := getEncoderFactory(c.Encoding); !ok {
+			errs = append(errs, &FieldError{
+				Field: "Encoding",
+				Value: c.Encoding,
+				Reason: fmt.Sprintf("value %q is not a registered encoder [%s]",
+					c.Encoding, strings.Join(encoderNames(), ",")),
+			})
+		}
+	}
+
+	if c.Mode == syslogMode && (c.Network == udpNetwork || c.Network == tcpNetwork) && len(c.Address) == 0 {
+		errs = append(errs, &FieldError{
+			Field:  "Address",
+			Value:  c.Address,
+			Reason: fmt.Sprintf("must be set for network %s", c.Network),
+		})
+	}
+
+	nonNegatives := []struct {
+		name  string
+		value int64
+	}{
+		{name: "MaxSize", value: int64(c.MaxSize)},
+		{name: "KeepDays", value: int64(c.KeepDays)},
+		{name: "MaxBackups", value: int64(c.MaxBackups)},
+		{name: "QueueSize", value: int64(c.QueueSize)},
+		{name: "FlushInterval", value: int64(c.FlushInterval)},
+		{name: "MaxSpoolSize", value: int64(c.MaxSpoolSize)},
+	}
+	for _, field := range nonNegatives {
+		if field.value < 0 {
+			errs = append(errs, &FieldError{
+				Field:  field.name,
+				Value:  field.value,
+				Reason: fmt.Sprintf("value %d must not be negative", field.value),
+			})
+		}
+	}
+
+	if len(errs) > 0 {
+		return &ConfError{Errors: errs}
+	}
+
+	return nil
+}
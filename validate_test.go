@@ -0,0 +1,47 @@
+package logx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogConfValidate(t *testing.T) {
+	assert.Nil(t, LogConf{}.Validate())
+	assert.Nil(t, LogConf{
+		Mode:      fileMode,
+		Encoding:  plainEncoding,
+		Level:     levelWarn,
+		Rotation:  hourlyRotation,
+		Overflow:  dropOldestOverflow,
+		QueueSize: 10,
+	}.Validate())
+
+	err := LogConf{
+		Mode:     "files",
+		Encoding: "text",
+		Level:    "trace",
+		MaxSize:  -1,
+	}.Validate()
+	assert.EqualError(t, err, `logx: invalid LogConf: `+
+		`Mode: value "files" is not in options [console,file,syslog]; `+
+		`Level: value "trace" is not in options [debug,info,warn,error,fatal]; `+
+		`Encoding: value "text" is not a registered encoder [json,logfmt,plain]; `+
+		`MaxSize: value -1 must not be negative`)
+
+	confErr, ok := err.(*ConfError)
+	assert.True(t, ok)
+	assert.Equal(t, 4, len(confErr.Errors))
+	assert.Equal(t, "Mode", confErr.Errors[0].Field)
+	assert.Equal(t, "files", confErr.Errors[0].Value)
+}
+
+func TestLoadInvalidConf(t *testing.T) {
+	err := Load(LogConf{Mode: "files"})
+	assert.IsType(t, &ConfError{}, err)
+
+	_, err = New(LogConf{Level: "verbose"})
+	assert.IsType(t, &ConfError{}, err)
+
+	assert.IsType(t, &ConfError{}, Reload(LogConf{Overflow: "drop"}))
+}
@@ -1,8 +1,6 @@
 package logx
 
 import (
-	"bytes"
-	"encoding/json"
 	"fmt"
 	"github.com/git-zjx/logx/color"
 	"io"
@@ -18,16 +16,26 @@ import (
 )
 
 const (
+	defaultTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+	defaultFilename   = "logx"
+
 	callerKey    = "caller"
 	callerDepth  = 5
 	contentKey   = "content"
+	errorKey     = "error"
 	levelKey     = "level"
 	timestampKey = "@timestamp"
 )
 
 var (
+	levelDebug = "debug"
 	levelInfo  = "info"
+	levelWarn  = "warn"
 	levelError = "error"
+	levelFatal = "fatal"
+
+	// levelNames are all the levels in ascending order
+	levelNames = []string{levelDebug, levelInfo, levelWarn, levelError, levelFatal}
 
 	flags = 0x0
 )
@@ -35,61 +43,251 @@ var (
 type (
 	Writer interface {
 		Close() error
-		Error(v interface{})
-		Info(v interface{})
+		Debug(v interface{}, fields ...LogField)
+		Error(v interface{}, fields ...LogField)
+		Fatal(v interface{}, fields ...LogField)
+		Info(v interface{}, fields ...LogField)
+		Warn(v interface{}, fields ...LogField)
 	}
 
 	atomicWriter struct {
-		writer Writer
-		lock   sync.RWMutex
+		current *heldWriter
+		lock    sync.RWMutex
+	}
+
+	// heldWriter counts the entries being written to writer, so that it's closed after them.
+	heldWriter struct {
+		writer   Writer
+		inflight sync.WaitGroup
 	}
 
 	defaultWriter struct {
+		// lw is nil in file mode if the levels are split without the combined file
 		lw io.WriteCloser
+		// levels are the writers of the levels not written to lw, like the per level files
+		// in file mode, or stderr in console mode
+		levels map[string]io.WriteCloser
+		// tee writes the entries of levels to lw as well
+		tee bool
+		// conf is nil for the package level writers, which follow the global encoder settings
+		conf *encoderConf
+		// level is nil for the package level writers, which follow the global log level
+		level *uint32
+	}
+
+	// encoderConf holds the encoder of the entries, it's immutable once created.
+	// The encoder is wrapped so that encoderSettings always stores the same concrete type.
+	encoderConf struct {
+		encoder Encoder
+	}
+
+	flusher interface {
+		Flush() error
+	}
+
+	dropCounter interface {
+		Dropped() uint64
+	}
+
+	asyncFlusher interface {
+		flushAsync()
+	}
+
+	leveledWriter interface {
+		shallLog(level uint32) bool
 	}
 )
 
 func (w *atomicWriter) Load() Writer {
 	w.lock.RLock()
 	defer w.lock.RUnlock()
-	return w.writer
+	if w.current == nil {
+		return nil
+	}
+
+	return w.current.writer
 }
 
 func (w *atomicWriter) Store(v Writer) {
 	w.lock.Lock()
 	defer w.lock.Unlock()
-	w.writer = v
+	w.current = &heldWriter{writer: v}
 }
 
 func (w *atomicWriter) StoreIfNil(v Writer) Writer {
 	w.lock.Lock()
 	defer w.lock.Unlock()
 
-	if w.writer == nil {
-		w.writer = v
+	if w.current == nil || w.current.writer == nil {
+		w.current = &heldWriter{writer: v}
 	}
 
-	return w.writer
+	return w.current.writer
 }
 
+// Swap replaces the writer with v, and returns the old one after the entries being written
+// to it are done, so that the old writer can be closed without losing them.
 func (w *atomicWriter) Swap(v Writer) Writer {
 	w.lock.Lock()
-	defer w.lock.Unlock()
-	old := w.writer
-	w.writer = v
-	return old
+	old := w.current
+	w.current = &heldWriter{writer: v}
+	w.lock.Unlock()
+
+	if old == nil {
+		return nil
+	}
+
+	old.inflight.Wait()
+	return old.writer
+}
+
+// hold returns the current writer, which is not returned by Swap until released.
+// It returns nil if no writer is set.
+func (w *atomicWriter) hold() *heldWriter {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	if w.current == nil || w.current.writer == nil {
+		return nil
+	}
+
+	w.current.inflight.Add(1)
+	return w.current
+}
+
+// release marks the entry written, h is nil for the writers not held.
+func (h *heldWriter) release() {
+	if h != nil {
+		h.inflight.Done()
+	}
 }
 
 func (w *defaultWriter) Close() error {
-	return w.lw.Close()
+	var errs writerErrors
+	for _, lw := range w.outputs() {
+		if err := lw.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errs.err()
+}
+
+// Flush flushes the underlying writers if they buffer entries, like DefaultLogger.
+func (w *defaultWriter) Flush() error {
+	var errs writerErrors
+	for _, lw := range w.outputs() {
+		if f, ok := lw.(flusher); ok {
+			if err := f.Flush(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return errs.err()
+}
+
+func (w *defaultWriter) Debug(v interface{}, fields ...LogField) {
+	output(w.writer(levelDebug), w.encoder(), levelDebug, v, fields...)
+}
+
+func (w *defaultWriter) Error(v interface{}, fields ...LogField) {
+	output(w.writer(levelError), w.encoder(), levelError, v, fields...)
+	w.flushAsync()
+}
+
+func (w *defaultWriter) Fatal(v interface{}, fields ...LogField) {
+	output(w.writer(levelFatal), w.encoder(), levelFatal, v, fields...)
+	w.flushAsync()
+}
+
+func (w *defaultWriter) Info(v interface{}, fields ...LogField) {
+	output(w.writer(levelInfo), w.encoder(), levelInfo, v, fields...)
+}
+
+func (w *defaultWriter) Warn(v interface{}, fields ...LogField) {
+	output(w.writer(levelWarn), w.encoder(), levelWarn, v, fields...)
+}
+
+// writeWithCaller writes the entry with the caller taken from the writers wrapping w, like Tee.
+func (w *defaultWriter) writeWithCaller(level uint32, caller string, v interface{}, fields []LogField) {
+	name := levelFatal
+	if level < uint32(len(levelNames)) {
+		name = levelNames[level]
+	}
+
+	outputWithCaller(w.writer(name), w.encoder(), name, caller, v, fields)
+	if level >= ErrorLevel {
+		w.flushAsync()
+	}
 }
 
-func (w *defaultWriter) Error(v interface{}) {
-	output(w.lw, levelError, v)
+// encoder returns the encoder settings of w.
+func (w *defaultWriter) encoder() *encoderConf {
+	if w.conf != nil {
+		return w.conf
+	}
+
+	return loadEncoderConf()
 }
 
-func (w *defaultWriter) Info(v interface{}) {
-	output(w.lw, levelInfo, v)
+// shallLog checks level against w's own level, or the global one if w has none.
+func (w *defaultWriter) shallLog(level uint32) bool {
+	if w.level != nil {
+		return *w.level <= level
+	}
+
+	return shallLog(level)
+}
+
+// flushAsync makes the buffered writers write out error entries promptly.
+func (w *defaultWriter) flushAsync() {
+	for _, lw := range w.outputs() {
+		if f, ok := lw.(asyncFlusher); ok {
+			f.flushAsync()
+		}
+	}
+}
+
+// Dropped returns the number of entries dropped by the underlying writers, like DefaultLogger.
+func (w *defaultWriter) Dropped() uint64 {
+	var dropped uint64
+	for _, lw := range w.outputs() {
+		if c, ok := lw.(dropCounter); ok {
+			dropped += c.Dropped()
+		}
+	}
+
+	return dropped
+}
+
+// writer returns where the entries of level go, both its own writer and lw if tee is set.
+func (w *defaultWriter) writer(level string) io.Writer {
+	lw, ok := w.levels[level]
+	switch {
+	case !ok:
+		return w.lw
+	case w.tee:
+		return io.MultiWriter(lw, w.lw)
+	default:
+		return lw
+	}
+}
+
+// outputs returns all the underlying writers, the ones shared by several levels are returned once.
+func (w *defaultWriter) outputs() []io.WriteCloser {
+	outputs := make([]io.WriteCloser, 0, len(w.levels)+1)
+	if w.lw != nil {
+		outputs = append(outputs, w.lw)
+	}
+	for _, level := range levelNames {
+		lw, ok := w.levels[level]
+		if ok && !containsWriter(outputs, lw) {
+			outputs = append(outputs, lw)
+		}
+	}
+
+	return outputs
 }
 
 func NewWriter(w io.Writer) Writer {
@@ -100,108 +298,316 @@ func NewWriter(w io.Writer) Writer {
 	}
 }
 
-func newConsoleWriter() Writer {
-	lw := newLogWriter(log.New(fatihColor.Output, "", flags))
+// NewWriterWithConf 返回写入 w 的 Writer，编码方式等使用 c 中的配置，不跟随全局配置，
+// 日志级别跟随全局配置，可以通过 LevelWriter 设置
+func NewWriterWithConf(w io.Writer, c LogConf) Writer {
+	lw := newLogWriter(log.New(w, "", flags))
+
 	return &defaultWriter{
-		lw: lw,
+		lw:   lw,
+		conf: newEncoderConf(c),
 	}
 }
 
-func newFileWriter(c LogConf, filename string) (Writer, error) {
-	var err error
-	var lw io.WriteCloser
+// newConsoleWriter creates the Writer of console mode, the warn, error and fatal entries go to stderr,
+// the others to stdout, all of them go to stdout if c.SingleStream is set.
+func newConsoleWriter(c LogConf) Writer {
+	w := &defaultWriter{
+		lw: newLogWriter(log.New(fatihColor.Output, "", flags)),
+	}
+	if c.SingleStream {
+		return w
+	}
+
+	stderr := newLogWriter(log.New(fatihColor.Error, "", flags))
+	w.levels = map[string]io.WriteCloser{
+		levelWarn:  stderr,
+		levelError: stderr,
+		levelFatal: stderr,
+	}
 
+	return w
+}
+
+// confFilename returns the name of the log files in c, without the extension.
+func confFilename(c LogConf) string {
+	if len(c.Filename) > 0 {
+		return c.Filename
+	}
+
+	return defaultFilename
+}
+
+// logFiles returns all the files the file writer of c might write, with or without the levels split.
+func logFiles(c LogConf, filename string) []string {
 	if len(c.Path) == 0 {
 		c.Path = "logs"
 	}
 
-	filePath := path.Join(c.Path, filename) + ".log"
+	files := []string{path.Join(c.Path, filename) + ".log"}
+	for _, level := range levelNames {
+		files = append(files, levelFilePath(c.Path, filename, level))
+	}
 
-	setupLogLevel(c)
+	return files
+}
 
-	if lw, err = createOutput(filePath); err != nil {
-		return nil, err
+func newFileWriter(c LogConf, filename string) (Writer, error) {
+	if len(c.Path) == 0 {
+		c.Path = "logs"
 	}
 
-	return &defaultWriter{
-		lw: lw,
-	}, nil
+	w := &defaultWriter{
+		tee: c.KeepCombined,
+	}
+	if !c.SplitByLevel || c.KeepCombined {
+		lw, err := createOutput(c, path.Join(c.Path, filename)+".log")
+		if err != nil {
+			return nil, err
+		}
+		w.lw = lw
+	}
+
+	if c.SplitByLevel {
+		if err := w.splitLevels(c, filename); err != nil {
+			_ = w.Close()
+			return nil, err
+		}
+	}
+
+	return w, nil
 }
 
-func createOutput(path string) (io.WriteCloser, error) {
-	return NewLogger(path)
+// splitLevels creates the files of the levels at or above c.Level, or the global level if not set.
+// The lower levels are logged only if the level is lowered later, they go to the combined file if kept,
+// otherwise to their own files created on the first entries, never to the files of the higher levels.
+func (w *defaultWriter) splitLevels(c LogConf, filename string) error {
+	minLevel, ok := parseLevel(c.Level)
+	if !ok {
+		minLevel = atomic.LoadUint32(&logLevel)
+	}
+	if minLevel >= uint32(len(levelNames)) {
+		minLevel = FatalLevel
+	}
+
+	w.levels = make(map[string]io.WriteCloser, len(levelNames))
+	for _, level := range levelNames[minLevel:] {
+		lw, err := createOutput(c, levelFilePath(c.Path, filename, level))
+		if err != nil {
+			return err
+		}
+		w.levels[level] = lw
+	}
+
+	if w.lw == nil {
+		for _, level := range levelNames[:minLevel] {
+			file := levelFilePath(c.Path, filename, level)
+			w.levels[level] = &lazyOutput{
+				create: func() (io.WriteCloser, error) {
+					return createOutput(c, file)
+				},
+			}
+		}
+	}
+
+	return nil
 }
 
-func output(writer io.Writer, level string, val interface{}) {
+// lazyOutput creates the output on the first write, so that the files of the levels not logged
+// are not created.
+type lazyOutput struct {
+	create func() (io.WriteCloser, error)
+	lock   sync.Mutex
+	output io.WriteCloser
+	err    error
+	closed bool
+}
 
-	switch atomic.LoadUint32(&encoding) {
-	case plainEncodingType:
-		writePlainAny(writer, level, val)
-	default:
-		entry := make(map[string]interface{})
-		entry[timestampKey] = getTimestamp()
-		entry[levelKey] = level
-		entry[contentKey] = val
-		entry[callerKey] = getCaller(callerDepth)
-		writeJson(writer, entry)
+func (o *lazyOutput) Write(p []byte) (int, error) {
+	output, err := o.get()
+	if err != nil {
+		return 0, err
 	}
+
+	return output.Write(p)
 }
 
-func writePlainAny(writer io.Writer, level string, val interface{}) {
-	if withColor {
-		level = wrapLevelWithColor(level)
+// Close closes the output if created, the later writes fail with ErrLogFileClosed.
+func (o *lazyOutput) Close() error {
+	o.lock.Lock()
+	o.closed = true
+	output := o.output
+	o.lock.Unlock()
+
+	if output == nil {
+		return nil
 	}
 
-	switch v := val.(type) {
-	case string:
-		writePlainText(writer, level, v)
-	case error:
-		writePlainText(writer, level, v.Error())
-	case fmt.Stringer:
-		writePlainText(writer, level, v.String())
+	return output.Close()
+}
+
+func (o *lazyOutput) Flush() error {
+	if f, ok := o.created().(flusher); ok {
+		return f.Flush()
+	}
+
+	return nil
+}
+
+func (o *lazyOutput) Dropped() uint64 {
+	if c, ok := o.created().(dropCounter); ok {
+		return c.Dropped()
+	}
+
+	return 0
+}
+
+func (o *lazyOutput) flushAsync() {
+	if f, ok := o.created().(asyncFlusher); ok {
+		f.flushAsync()
+	}
+}
+
+// created returns the output, nil if not created yet.
+func (o *lazyOutput) created() io.WriteCloser {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	return o.output
+}
+
+// get returns the output, creating it on the first call. The creation is not retried if it fails.
+func (o *lazyOutput) get() (io.WriteCloser, error) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	if o.output == nil && o.err == nil {
+		if o.closed {
+			return nil, ErrLogFileClosed
+		}
+
+		o.output, o.err = o.create()
+		if o.err != nil {
+			log.Printf("logx: create log file failed: %s", o.err.Error())
+		}
+	}
+
+	return o.output, o.err
+}
+
+func containsWriter(writers []io.WriteCloser, w io.WriteCloser) bool {
+	for _, each := range writers {
+		if each == w {
+			return true
+		}
+	}
+
+	return false
+}
+
+// levelFilePath returns the file of level, like logs/logx-error.log, prefixed by the filename
+// so that it never clashes with the files of NewFileLogger.
+func levelFilePath(dir, filename, level string) string {
+	return path.Join(dir, filename+"-"+level) + ".log"
+}
+
+// newModeWriter creates the Writer for c.Mode, console is used for unknown modes.
+func newModeWriter(c LogConf) (Writer, error) {
+	switch c.Mode {
+	case fileMode:
+		return newFileWriter(c, confFilename(c))
+	case syslogMode:
+		return newSyslogWriter(c)
 	default:
-		writePlainValue(writer, level, v)
+		return newConsoleWriter(c), nil
 	}
 }
 
-func writePlainText(writer io.Writer, level, msg string) {
-	var buf bytes.Buffer
-	buf.WriteString(getTimestamp())
-	buf.WriteString(plainEncodingSep)
-	buf.WriteString(level)
-	buf.WriteString(plainEncodingSep)
-	buf.WriteString(msg)
-	buf.WriteString(plainEncodingSep)
-	buf.WriteString(fmt.Sprintf("%s=%v", callerKey, getCaller(callerDepth)))
-	buf.WriteByte('\n')
-	if writer == nil {
-		log.Println(buf.String())
-		return
+// newScopedWriter creates the Writer for c.Mode, with its own encoder settings and level.
+// The files already open by the other loggers are rejected, since the loggers would interleave
+// their entries and rotate the files independently.
+func newScopedWriter(c LogConf) (Writer, error) {
+	if c.Mode == fileMode {
+		if file, ok := openedFile(logFiles(c, confFilename(c))); ok {
+			return nil, fmt.Errorf("logx: %s is already open by another logger, set another Path or Filename", file)
+		}
 	}
 
-	if _, err := writer.Write(buf.Bytes()); err != nil {
-		log.Println(err.Error())
+	w, err := newModeWriter(c)
+	if err != nil {
+		return nil, err
+	}
+
+	dw := w.(*defaultWriter)
+	// the writers of some modes have their own encoder, like syslog
+	if dw.conf == nil {
+		dw.conf = newEncoderConf(c)
+	}
+	level := InfoLevel
+	if lv, ok := parseLevel(c.Level); ok {
+		level = lv
+	}
+	dw.level = &level
+
+	return dw, nil
+}
+
+func createOutput(c LogConf, path string) (io.WriteCloser, error) {
+	return newLogger(path, c)
+}
+
+// newEncoderConf returns the encoder settings in c, json is used for the empty or unknown encodings.
+func newEncoderConf(c LogConf) *encoderConf {
+	factory, ok := getEncoderFactory(c.Encoding)
+	if !ok {
+		factory, _ = getEncoderFactory(jsonEncoding)
+	}
+
+	return &encoderConf{
+		encoder: factory(c),
+	}
+}
+
+// loadEncoderConf returns the global encoder settings.
+func loadEncoderConf() *encoderConf {
+	if ec, ok := encoderSettings.Load().(*encoderConf); ok {
+		return ec
 	}
+
+	return defaultEncoderConf
 }
 
-func writePlainValue(writer io.Writer, level string, val interface{}) {
-	var buf bytes.Buffer
-	buf.WriteString(getTimestamp())
-	buf.WriteString(plainEncodingSep)
-	buf.WriteString(level)
-	buf.WriteString(plainEncodingSep)
-	if err := json.NewEncoder(&buf).Encode(val); err != nil {
+// storeEncoderConf replaces the global encoder settings.
+func storeEncoderConf(ec *encoderConf) {
+	encoderSettings.Store(ec)
+}
+
+func output(writer io.Writer, ec *encoderConf, level string, val interface{}, fields ...LogField) {
+	outputWithCaller(writer, ec, level, getCaller(callerDepth), val, fields)
+}
+
+func outputWithCaller(writer io.Writer, ec *encoderConf, level, caller string, val interface{}, fields []LogField) {
+	entry := Entry{
+		Time:    time.Now(),
+		Level:   level,
+		Caller:  caller,
+		Content: val,
+		Fields:  fields,
+	}
+
+	// the buffer is reused once Write returns, writers must not retain it, see io.Writer
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if err := ec.encoder.Encode(buf, entry); err != nil {
 		log.Println(err.Error())
 		return
 	}
-	buf.WriteString(plainEncodingSep)
-	buf.WriteString(fmt.Sprintf("%s=%v", callerKey, getCaller(callerDepth)))
-	buf.WriteByte('\n')
+
 	if writer == nil {
 		log.Println(buf.String())
 		return
 	}
 
+	buf.WriteByte('\n')
 	if _, err := writer.Write(buf.Bytes()); err != nil {
 		log.Println(err.Error())
 	}
@@ -210,10 +616,16 @@ func writePlainValue(writer io.Writer, level string, val interface{}) {
 func wrapLevelWithColor(level string) string {
 	var colour color.Color
 	switch level {
-	case levelError:
-		colour = color.FgRed
+	case levelDebug:
+		colour = color.FgCyan
 	case levelInfo:
 		colour = color.FgBlue
+	case levelWarn:
+		colour = color.FgYellow
+	case levelError:
+		colour = color.FgRed
+	case levelFatal:
+		colour = color.BgRed
 	}
 
 	if colour == color.NoColor {
@@ -223,16 +635,6 @@ func wrapLevelWithColor(level string) string {
 	return color.WithColorPadding(level, colour)
 }
 
-func writeJson(writer io.Writer, info interface{}) {
-	if content, err := json.Marshal(info); err != nil {
-		log.Println(err.Error())
-	} else if writer == nil {
-		log.Println(string(content))
-	} else {
-		_, _ = writer.Write(append(content, '\n'))
-	}
-}
-
 func getCaller(callDepth int) string {
 	_, file, line, ok := runtime.Caller(callDepth)
 	if !ok {
@@ -242,10 +644,6 @@ func getCaller(callDepth int) string {
 	return prettyCaller(file, line)
 }
 
-func getTimestamp() string {
-	return time.Now().Format(timeFormat)
-}
-
 func prettyCaller(file string, line int) string {
 	idx := strings.LastIndexByte(file, '/')
 	if idx < 0 {
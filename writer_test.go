@@ -6,6 +6,9 @@ import (
 	"errors"
 	"github.com/stretchr/testify/assert"
 	"log"
+	"os"
+	"path"
+	"strings"
 	"testing"
 )
 
@@ -19,7 +22,7 @@ func TestNewWriter(t *testing.T) {
 
 func TestConsoleWriter(t *testing.T) {
 	var buf bytes.Buffer
-	w := newConsoleWriter()
+	w := newConsoleWriter(LogConf{SingleStream: true})
 	lw := newLogWriter(log.New(&buf, "", 0))
 	w.(*defaultWriter).lw = lw
 	w.Error("foo bar 1")
@@ -44,24 +47,50 @@ func TestConsoleWriter(t *testing.T) {
 	w.(*defaultWriter).lw = easyToCloseWriter{}
 }
 
+func TestConsoleWriterStderr(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	w := newConsoleWriter(LogConf{}).(*defaultWriter)
+	w.lw = newLogWriter(log.New(&stdout, "", 0))
+	errLw := newLogWriter(log.New(&stderr, "", 0))
+	for level := range w.levels {
+		w.levels[level] = errLw
+	}
+
+	w.Debug("debug")
+	w.Info("info")
+	w.Warn("warn")
+	w.Error("error")
+	w.Fatal("fatal")
+	assert.Equal(t, 2, strings.Count(stdout.String(), "\n"))
+	assert.Contains(t, stdout.String(), `"debug"`)
+	assert.Contains(t, stdout.String(), `"info"`)
+	assert.Equal(t, 3, strings.Count(stderr.String(), "\n"))
+	assert.Contains(t, stderr.String(), `"warn"`)
+	assert.Contains(t, stderr.String(), `"error"`)
+	assert.Contains(t, stderr.String(), `"fatal"`)
+	assert.Len(t, w.outputs(), 2)
+	assert.Nil(t, w.Close())
+}
+
 func TestWriteJson(t *testing.T) {
 	var buf bytes.Buffer
 	log.SetOutput(&buf)
-	writeJson(nil, "foo")
+	output(nil, defaultEncoderConf, levelInfo, "foo")
 	assert.Contains(t, buf.String(), "foo")
 	buf.Reset()
-	writeJson(nil, make(chan int))
+	output(nil, defaultEncoderConf, levelInfo, make(chan int))
 	assert.Contains(t, buf.String(), "unsupported type")
 }
 
 func TestWritePlainAny(t *testing.T) {
 	var buf bytes.Buffer
 	log.SetOutput(&buf)
-	writePlainAny(nil, levelInfo, "foo")
+	ec := newEncoderConf(LogConf{Encoding: plainEncoding})
+	output(nil, ec, levelInfo, "foo")
 	assert.Contains(t, buf.String(), "foo")
 
 	buf.Reset()
-	writePlainAny(nil, levelError, make(chan int))
+	output(nil, ec, levelError, make(chan int))
 	assert.Contains(t, buf.String(), "unsupported type")
 }
 
@@ -95,3 +124,73 @@ type hardToWriteWriter struct{}
 func (h hardToWriteWriter) Write(_ []byte) (_ int, _ error) {
 	return 0, errors.New("write error")
 }
+
+func TestFileWriterSplitByLevel(t *testing.T) {
+	dir := t.TempDir()
+	w, err := newFileWriter(LogConf{
+		Path:         dir,
+		Level:        levelInfo,
+		SplitByLevel: true,
+	}, defaultFilename)
+	assert.Nil(t, err)
+	w.Info("foo")
+	w.Error("bar")
+	// logged only if the level is lowered after the writer is created, to its own file created on demand
+	_, err = os.Stat(path.Join(dir, "logx-debug.log"))
+	assert.True(t, os.IsNotExist(err))
+	w.Debug("baz")
+	assert.Nil(t, w.Close())
+
+	info, err := os.ReadFile(path.Join(dir, "logx-info.log"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(info), "foo")
+	assert.NotContains(t, string(info), "baz")
+	assert.NotContains(t, string(info), "bar")
+	debug, err := os.ReadFile(path.Join(dir, "logx-debug.log"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(debug), "baz")
+	assert.NotContains(t, string(debug), "foo")
+	errs, err := os.ReadFile(path.Join(dir, "logx-error.log"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(errs), "bar")
+	assert.NotContains(t, string(errs), "foo")
+	for _, file := range []string{"logx.log", "debug.log", "info.log"} {
+		_, err = os.Stat(path.Join(dir, file))
+		assert.True(t, os.IsNotExist(err), file)
+	}
+	_, err = os.Stat(path.Join(dir, "logx-fatal.log"))
+	assert.Nil(t, err)
+}
+
+func TestFileWriterSplitByLevelKeepCombined(t *testing.T) {
+	dir := t.TempDir()
+	w, err := newFileWriter(LogConf{
+		Path:         dir,
+		Level:        levelError,
+		SplitByLevel: true,
+		KeepCombined: true,
+	}, "audit")
+	assert.Nil(t, err)
+	w.Info("foo")
+	w.Error("bar")
+	assert.Nil(t, w.(flusher).Flush())
+	assert.Equal(t, uint64(0), w.(dropCounter).Dropped())
+	assert.Nil(t, w.Close())
+
+	errs, err := os.ReadFile(path.Join(dir, "audit-error.log"))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, strings.Count(string(errs), "\n"))
+	assert.Contains(t, string(errs), "bar")
+	all, err := os.ReadFile(path.Join(dir, "audit.log"))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, strings.Count(string(all), "\n"))
+	assert.Contains(t, string(all), "foo")
+	assert.Contains(t, string(all), "bar")
+	_, err = os.Stat(path.Join(dir, "audit-info.log"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestLevelFilePath(t *testing.T) {
+	assert.Equal(t, "logs/logx-error.log", levelFilePath("logs", defaultFilename, levelError))
+	assert.Equal(t, "logs/audit-error.log", levelFilePath("logs", "audit", levelError))
+}